@@ -0,0 +1,231 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package extensions
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+var htmlExtensions = []string{"html", "htm"}
+var markdownExtensions = []string{"md", "markdown"}
+
+// lineRange matches the fragments choosing lines of a source file, such as L10-L40, L10-40, or L10.
+var lineRange = regexp.MustCompile(`^L(\d+)(?:-L?(\d+))?$`)
+
+// embedFile makes the node for ![%](destination). SVG and HTML files are written into the page as they are, markdown
+// files are parsed and rendered in place, and source files are shown as highlighted code blocks. A fragment chooses
+// lines of a source file, either as a range like #L10-L40 or as a region named by #region and #endregion comments.
+// Any other file, such as an image, is not embedded, and nil is returned.
+func (e *mediaEmbed) embedFile(destination []byte) *media {
+	file, fragment, _ := bytes.Cut(destination, []byte{'#'})
+	path := strings.TrimLeft(string(file), "/")
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	var out *media
+	var err error
+	switch {
+	case ext == "svg":
+		out = NewMedia(mediaInfo{ext, file}, mediaSVG)
+	case slices.Contains(htmlExtensions, ext):
+		out = NewMedia(mediaInfo{ext, file}, mediaHTML)
+	case slices.Contains(markdownExtensions, ext):
+		out = NewMedia(mediaInfo{ext, destination}, mediaDocument)
+		out.doc, out.source, err = e.transclude(path)
+	default:
+		var data []byte
+		data, err = os.ReadFile(path)
+		if err == nil && !isSource(path, data) {
+			return nil
+		}
+		out = NewMedia(mediaInfo{ext, destination}, mediaDocument)
+		if err == nil {
+			out.doc, out.source, err = e.codeDocument(path, string(fragment), data)
+		}
+	}
+	if e.sourceEmbeds != nil {
+		*(e.sourceEmbeds) = append(*(e.sourceEmbeds), path)
+	}
+	if err != nil {
+		log.Printf("WARN: could not embed %s: %s\n", string(destination), err.Error())
+	}
+	return out
+}
+
+// isSource tells whether a file can be shown as code: either chroma knows its language or it is text.
+func isSource(path string, data []byte) bool {
+	if lexers.Match(filepath.Base(path)) != nil {
+		return true
+	}
+	return utf8.Valid(data) && !bytes.ContainsRune(data, 0)
+}
+
+// transclude parses a markdown file to be rendered in place of the image embedding it. A file that is already being
+// embedded is refused, so that files cannot embed each other forever.
+func (e *mediaEmbed) transclude(path string) (ast.Node, []byte, error) {
+	if slices.Contains(e.embedding, path) {
+		return nil, nil, fmt.Errorf("%s embeds itself", path)
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.embedding = append(e.embedding, path)
+	defer func() { e.embedding = e.embedding[:len(e.embedding)-1] }()
+	return e.markdown.Parser().Parse(text.NewReader(source)), source, nil
+}
+
+// codeDocument makes a markdown document of a single code block holding data, the content of a source file, or the
+// lines of it chosen by fragment, captioned with the file's name and numbered as in the file.
+func (e *mediaEmbed) codeDocument(path string, fragment string, data []byte) (ast.Node, []byte, error) {
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	first, last, err := selectLines(lines, fragment)
+	if err != nil {
+		return nil, nil, err
+	}
+	code := dedent(lines[first:last])
+	lang := "text"
+	if lexer := lexers.Match(filepath.Base(path)); lexer != nil {
+		config := lexer.Config()
+		lang = strings.ToLower(config.Name)
+		if len(config.Aliases) > 0 {
+			lang = config.Aliases[0]
+		}
+	}
+	attrs := fmt.Sprintf("filename=%q", filepath.Base(path))
+	if first > 0 {
+		attrs += " linenostart=" + strconv.Itoa(first+1)
+	}
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	source := []byte(fence + lang + " {" + attrs + "}\n" + code + "\n" + fence + "\n")
+	return e.markdown.Parser().Parse(text.NewReader(source)), source, nil
+}
+
+// selectLines finds the lines chosen by fragment, as the index of the first and one past the last.
+func selectLines(lines []string, fragment string) (int, int, error) {
+	if fragment == "" {
+		return 0, len(lines), nil
+	}
+	if match := lineRange.FindStringSubmatch(fragment); match != nil {
+		start, _ := strconv.Atoi(match[1])
+		end := start
+		if match[2] != "" {
+			end, _ = strconv.Atoi(match[2])
+		}
+		if start < 1 || start > len(lines) || end < start {
+			return 0, 0, fmt.Errorf("the file has no lines %s", fragment)
+		}
+		return start - 1, min(end, len(lines)), nil
+	}
+	for i, line := range lines {
+		if regionName(line) != fragment {
+			continue
+		}
+		depth := 0
+		for j := i + 1; j < len(lines); j++ {
+			switch {
+			case strings.Contains(lines[j], "#endregion") && depth == 0:
+				return i + 1, j, nil
+			case strings.Contains(lines[j], "#endregion"):
+				depth--
+			case strings.Contains(lines[j], "#region"):
+				depth++
+			}
+		}
+		return 0, 0, fmt.Errorf("the region %s is never closed with #endregion", fragment)
+	}
+	return 0, 0, fmt.Errorf("the file has no region %s", fragment)
+}
+
+// regionName gives the name of the region a line such as "// #region setup" begins, if it begins one.
+func regionName(line string) string {
+	_, after, found := strings.Cut(line, "#region")
+	if !found {
+		return ""
+	}
+	fields := strings.Fields(after)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// dedent joins lines after removing the indentation they all share, ignoring blank lines.
+func dedent(lines []string) string {
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.TrimPrefix(line, prefix)
+	}
+	return strings.Join(out, "\n")
+}
+
+// longestRun gives the length of the longest run of c in s.
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
@@ -35,6 +35,7 @@ package extensions
 
 import (
 	"bytes"
+	"log"
 	"os"
 	"slices"
 	"strings"
@@ -53,6 +54,8 @@ const (
 	mediaAudio = iota
 	mediaVideo
 	mediaSVG
+	mediaHTML
+	mediaDocument // A markdown or source file, parsed as a document of its own and rendered in place
 )
 
 type mediaInfo struct {
@@ -64,6 +67,8 @@ type media struct {
 	ast.BaseBlock
 	info   mediaInfo
 	medium mediaType
+	doc    ast.Node // The embedded document, which may be nil if the file could not be read
+	source []byte   // The source of the embedded document
 }
 
 var KindMedia = ast.NewNodeKind("Media")
@@ -77,6 +82,11 @@ func (n *media) Dump(source []byte, level int) {
 	ast.DumpHelper(n, source, level, nil, nil)
 }
 
+// inline tells whether the media may be placed within a paragraph. Embedded HTML and documents are blocks of their own.
+func (n *media) inline() bool {
+	return n.medium != mediaHTML && n.medium != mediaDocument
+}
+
 func NewMedia(i mediaInfo, t mediaType) *media {
 	return &media{
 		info:   i,
@@ -84,15 +94,82 @@ func NewMedia(i mediaInfo, t mediaType) *media {
 	}
 }
 
+var mediaMIMETypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"ogg":  "audio/ogg",
+	"wav":  "audio/wav",
+	"flac": "audio/flac",
+	"webm": "video/webm",
+	"mp4":  "video/mp4",
+	"mkv":  "video/x-matroska",
+	"ogv":  "video/ogg",
+}
+
+var videoExtensions = []string{"webm", "mp4", "mkv", "ogv"}
+var audioExtensions = []string{"mp3", "ogg", "wav", "flac"}
+
+// MediaMIMEType gives the MIME type of an audio or video file from its extension, without the leading dot. ok is
+// false for anything that would not be embedded as audio or video.
+func MediaMIMEType(ext string) (mimeType string, ok bool) {
+	ext = strings.ToLower(ext)
+	if !slices.Contains(videoExtensions, ext) && !slices.Contains(audioExtensions, ext) {
+		return "", false
+	}
+	return mediaMIMETypes[ext], true
+}
+
+// NewMediaNode creates the same node that an image linking to destination would become, so that audio and video can
+// be rendered outside of a markdown document. ok is false if destination is not an audio or video file.
+func NewMediaNode(destination string) (node ast.Node, ok bool) {
+	dotidx := strings.LastIndexByte(destination, '.')
+	if dotidx < 0 {
+		return nil, false
+	}
+	ext := destination[dotidx+1:]
+	if slices.Contains(videoExtensions, ext) {
+		return NewMedia(mediaInfo{ext, []byte(destination)}, mediaVideo), true
+	}
+	if slices.Contains(audioExtensions, ext) {
+		return NewMedia(mediaInfo{ext, []byte(destination)}, mediaAudio), true
+	}
+	return nil, false
+}
+
+// MediaInfo reports the destination and MIME type of an embedded audio or video node. ok is false for any other kind
+// of node, including embedded files.
+func MediaInfo(n ast.Node) (destination string, mimeType string, ok bool) {
+	m, isMedia := n.(*media)
+	if !isMedia || (m.medium != mediaAudio && m.medium != mediaVideo) {
+		return "", "", false
+	}
+	mimeType, found := mediaMIMETypes[m.info.ext]
+	if !found {
+		if m.medium == mediaVideo {
+			mimeType = "video/" + m.info.ext
+		} else {
+			mimeType = "audio/" + m.info.ext
+		}
+	}
+	return string(m.info.destination), mimeType, true
+}
+
+// EmbeddedDocument gives the document embedded by a node made from ![%](file) for a markdown or source file, along
+// with its source. ok is false for any other node.
+func EmbeddedDocument(n ast.Node) (doc ast.Node, source []byte, ok bool) {
+	m, isMedia := n.(*media)
+	if !isMedia || m.medium != mediaDocument || m.doc == nil {
+		return nil, nil, false
+	}
+	return m.doc, m.source, true
+}
+
 // var contextKeySnippet = parser.NewContextKey()
 type mediaTransformer struct {
-	sourceEmbeds *[]string
+	embed *mediaEmbed
 }
 
 func (r mediaTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
 	//var buf bytes.Buffer
-	VideoExt := []string{"webm", "mp4", "mkv", "ogv"}
-	AudioExt := []string{"mp3", "ogg", "wav", "flac"}
 	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if entering && n.Kind() == ast.KindImage {
 			//var imagenode bytes.Buffer
@@ -102,44 +179,88 @@ func (r mediaTransformer) Transform(node *ast.Document, reader text.Reader, pc p
 				ext := string(img.Destination[dotidx+1:])
 				isMedia := false
 				var flavor mediaType
-				if slices.Contains(VideoExt, ext) {
+				if slices.Contains(videoExtensions, ext) {
 					flavor = mediaVideo
 					isMedia = true
-				} else if slices.Contains(AudioExt, ext) {
+				} else if slices.Contains(audioExtensions, ext) {
 					flavor = mediaAudio
 					isMedia = true
-				} else if ext == "svg" && slices.Equal(img.Text(reader.Source()), []byte{'%'}) {
-					flavor = mediaSVG
-					if r.sourceEmbeds != nil {
-						*(r.sourceEmbeds) = append(*(r.sourceEmbeds), strings.TrimLeft(string(img.Destination), "/"))
-					}
-					isMedia = true
 				}
 				if isMedia {
 					n.Parent().ReplaceChild(n.Parent(), n, NewMedia(mediaInfo{ext, img.Destination}, flavor))
+					return ast.WalkContinue, nil
+				}
+			}
+			if slices.Equal(img.Text(reader.Source()), []byte{'%'}) {
+				// A block can only be split out of a paragraph, not out of emphasis, a link, or a heading.
+				parent := n.Parent().Kind()
+				canHoldBlock := parent == ast.KindParagraph || parent == ast.KindTextBlock
+				if embedded := r.embed.embedFile(img.Destination); embedded != nil && (embedded.inline() || canHoldBlock) {
+					n.Parent().ReplaceChild(n.Parent(), n, embedded)
 				}
 			}
 		}
 		return ast.WalkContinue, nil
 	})
-	// If the media is the only child of a paragraph, replace the paragraph with the media.
+	// If the media is the only child of a paragraph, replace the paragraph with the media. Blocks are taken out of the
+	// paragraph they were written in, which is split around them.
+	split := make([]*media, 0)
 	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if entering && n.Kind() == KindMedia {
-			if n.Parent().Kind() == ast.KindParagraph && n.Parent().ChildCount() == 1 {
-				n.Parent().Parent().ReplaceChild(
-					n.Parent().Parent(),
-					n.Parent(),
-					n,
-				)
+			m := n.(*media)
+			if n.Parent().Kind() == ast.KindParagraph && (n.Parent().ChildCount() == 1 || !m.inline()) {
+				split = append(split, m)
 			}
 		}
 		return ast.WalkContinue, nil
 	})
+	for _, m := range split {
+		splitParagraph(m, reader.Source())
+	}
+}
+
+// splitParagraph moves n out of its paragraph to just after it, and whatever followed n into a new paragraph after n.
+// A paragraph left empty is removed.
+func splitParagraph(n ast.Node, source []byte) {
+	para := n.Parent()
+	parent := para.Parent()
+	after := ast.NewParagraph()
+	for child := n.NextSibling(); child != nil; {
+		next := child.NextSibling()
+		after.AppendChild(after, child)
+		child = next
+	}
+	para.RemoveChild(para, n)
+	parent.InsertAfter(parent, para, n)
+	// The spaces and line breaks around n are dropped along with it.
+	for t, ok := para.LastChild().(*ast.Text); ok; t, ok = para.LastChild().(*ast.Text) {
+		t.Segment = t.Segment.TrimRightSpace(source)
+		t.SetSoftLineBreak(false)
+		if !t.Segment.IsEmpty() {
+			break
+		}
+		para.RemoveChild(para, t)
+	}
+	for t, ok := after.FirstChild().(*ast.Text); ok; t, ok = after.FirstChild().(*ast.Text) {
+		t.Segment = t.Segment.TrimLeftSpace(source)
+		if !t.Segment.IsEmpty() {
+			break
+		}
+		after.RemoveChild(after, t)
+	}
+	if after.HasChildren() {
+		parent.InsertAfter(parent, n, after)
+	}
+	if !para.HasChildren() {
+		parent.RemoveChild(parent, para)
+	}
 }
 
 // Create Renderer
-// MediaHTMLRenderer is a renderer for video nodes.
-type MediaHTMLRenderer struct{}
+// MediaHTMLRenderer is a renderer for video nodes. It renders embedded documents with markdown, if it has one.
+type MediaHTMLRenderer struct {
+	markdown goldmark.Markdown
+}
 
 // NewMediaHTMLRenderer returns a new MediaHTMLRenderer.
 func NewMediaHTMLRenderer() renderer.NodeRenderer {
@@ -170,13 +291,14 @@ func (r *MediaHTMLRenderer) renderMedia(w util.BufWriter, source []byte, node as
 		tagOpen = `<audio controls>`
 		tagClose = `</audio>`
 		mime = "audio"
-	case mediaSVG:
+	case mediaSVG, mediaHTML:
 		if !entering {
 			return ast.WalkContinue, nil
 		}
 		//remove the leading slash
-		svg, err := os.Open(string(n.info.destination[1:]))
+		svg, err := os.Open(strings.TrimLeft(string(n.info.destination), "/"))
 		if err != nil {
+			log.Printf("WARN: could not embed %s: %s\n", string(n.info.destination), err.Error())
 			return ast.WalkContinue, nil
 		}
 		defer svg.Close()
@@ -185,6 +307,13 @@ func (r *MediaHTMLRenderer) renderMedia(w util.BufWriter, source []byte, node as
 		//w.Write(buf.Bytes())
 		svg.WriteTo(w)
 		return ast.WalkContinue, nil
+	case mediaDocument:
+		if entering && n.doc != nil && r.markdown != nil {
+			if err := r.markdown.Renderer().Render(w, n.source, n.doc); err != nil {
+				return ast.WalkStop, err
+			}
+		}
+		return ast.WalkContinue, nil
 	}
 
 	mime = strings.Join([]string{mime, n.info.ext}, "/")
@@ -205,21 +334,26 @@ func (r *MediaHTMLRenderer) renderMedia(w util.BufWriter, source []byte, node as
 	return ast.WalkContinue, nil
 }
 
-type mediaEmbed struct{ sourceEmbeds *[]string }
+type mediaEmbed struct {
+	sourceEmbeds *[]string
+	markdown     goldmark.Markdown // Parses and renders embedded markdown and source files
+	embedding    []string          // The files being embedded, innermost last
+}
 
 func (e *mediaEmbed) Extend(m goldmark.Markdown) {
+	e.markdown = m
 	m.Parser().AddOptions(
 		parser.WithASTTransformers(
-			util.Prioritized(mediaTransformer{(*e).sourceEmbeds}, priorityMediaTransformer),
+			util.Prioritized(mediaTransformer{e}, priorityMediaTransformer),
 		),
 	)
 	m.Renderer().AddOptions(
 		renderer.WithNodeRenderers(
-			util.Prioritized(NewMediaHTMLRenderer(), priorityMediaHTMLRenderer),
+			util.Prioritized(&MediaHTMLRenderer{markdown: m}, priorityMediaHTMLRenderer),
 		),
 	)
 }
 
 func EmbedMedia(sourceEmbeds *[]string) goldmark.Extender {
-	return &mediaEmbed{sourceEmbeds}
+	return &mediaEmbed{sourceEmbeds: sourceEmbeds}
 }
@@ -87,6 +87,10 @@ const (
 	priorityAttribListParser       = 2000
 	priorityAttribListTransformer  = 1000
 	priorityLinkRewriteTransformer = 0
+	priorityMathBlockParser        = 750 //Must be before paragraphs
+	priorityMathParser             = 150
+	priorityMathRenderer           = 1000
+	priorityMathTransformer        = 1000
 	priorityMediaHTMLRenderer      = 10000
 	priorityMediaTransformer       = 9000
 )
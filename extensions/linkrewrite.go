@@ -35,6 +35,7 @@ package extensions
 
 import (
 	"log"
+	"strings"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
@@ -59,11 +60,16 @@ func (r linkRewriteTransformer) Transform(node *ast.Document, reader text.Reader
 			case ast.KindImage:
 				url = &n.(*ast.Image).Destination
 			}
-			temp, err := util.RewriteURLPath(string(*url), r.subdir)
+			// A fragment, as in file.md#section, is kept as it is while the rest is rewritten.
+			dest, fragment := string(*url), ""
+			if idx := strings.IndexByte(dest, '#'); idx > 0 {
+				dest, fragment = dest[:idx], dest[idx:]
+			}
+			temp, err := util.RewriteURLPath(dest, r.subdir)
 			if err != nil {
 				log.Printf("Error transforming URL '%s' : %s\n", string(*url), err.Error())
 			}
-			*url = []byte(temp)
+			*url = []byte(temp + fragment)
 		}
 		return ast.WalkContinue, nil
 	})
@@ -0,0 +1,406 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package extensions
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// mathContent is the TeX of an equation and what it becomes.
+type mathContent struct {
+	tex     string
+	display bool
+	result  texResult
+	number  string // The number of a numbered display equation
+	ref     string // The label, if the math is nothing but a reference to another equation
+	refTo   string // The number of the equation referred to
+	eqref   bool   // The reference is an \eqref, which is printed in parentheses
+	err     error
+}
+
+type mathNode struct {
+	ast.BaseInline
+	mathContent
+}
+
+var KindMath = ast.NewNodeKind("Math")
+
+func (n *mathNode) Kind() ast.NodeKind {
+	return KindMath
+}
+
+// Dump implements Node.Dump.
+func (n *mathNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"tex": n.tex}, nil)
+}
+
+type mathBlockNode struct {
+	ast.BaseBlock
+	mathContent
+	closed bool
+}
+
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+func (n *mathBlockNode) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+func (n *mathBlockNode) IsRaw() bool {
+	return true
+}
+
+// Dump implements Node.Dump.
+func (n *mathBlockNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"tex": n.tex}, nil)
+}
+
+var mathDelimiter = []byte("$$")
+
+// mathParser finds $inline$ math, and $$display$$ math within a paragraph.
+type mathParser struct{}
+
+func (p *mathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathParser) Parse(parent ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if bytes.HasPrefix(line, mathDelimiter) {
+		stop := bytes.Index(line[2:], mathDelimiter)
+		if stop < 0 || len(bytes.TrimSpace(line[2:2+stop])) == 0 {
+			return nil
+		}
+		block.Advance(stop + 4)
+		return &mathNode{mathContent: mathContent{tex: string(line[2 : 2+stop]), display: true}}
+	}
+	// As in Pandoc, the opening $ must be followed by something other than a space, and the closing one must follow
+	// something other than a space and not be followed by a digit, so that prices are left alone. Math cannot hold
+	// an unescaped $, so one that cannot close it means this was not math after all.
+	if len(line) < 3 || line[1] == ' ' || line[1] == '\t' {
+		return nil
+	}
+	for i := 2; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '$':
+			if line[i-1] == ' ' || line[i-1] == '\t' || i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+				return nil
+			}
+			block.Advance(i + 1)
+			return &mathNode{mathContent: mathContent{tex: string(line[1:i])}}
+		}
+	}
+	return nil
+}
+
+// mathBlockParser finds display math on lines of its own, beginning and ending with $$.
+type mathBlockParser struct{}
+
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathDelimiter) {
+		return nil, parser.NoChildren
+	}
+	rest := line[pos+2:]
+	node := &mathBlockNode{mathContent: mathContent{display: true}}
+	if stop := bytes.Index(rest, mathDelimiter); stop >= 0 {
+		// Anything after the closing $$ makes this a paragraph with display math in it.
+		if len(bytes.TrimSpace(rest[stop+2:])) > 0 {
+			return nil, parser.NoChildren
+		}
+		node.tex = string(rest[:stop])
+		node.closed = true
+	} else {
+		node.tex = string(rest)
+	}
+	reader.Advance(restOfLine(line, segment))
+	return node, parser.NoChildren
+}
+
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*mathBlockNode)
+	if n.closed {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	if line == nil {
+		return parser.Close
+	}
+	if stop := bytes.Index(line, mathDelimiter); stop >= 0 {
+		n.tex += string(line[:stop])
+		n.closed = true
+		reader.Advance(restOfLine(line, segment))
+		return parser.Close
+	}
+	n.tex += string(line)
+	reader.Advance(restOfLine(line, segment))
+	return parser.Continue | parser.NoChildren
+}
+
+// restOfLine gives how far to advance past a line, short of its newline, which may be missing from the last line.
+func restOfLine(line []byte, segment text.Segment) int {
+	if bytes.HasSuffix(line, []byte{'\n'}) {
+		return segment.Len() - 1 - segment.Padding
+	}
+	return segment.Len() - segment.Padding
+}
+
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	n := node.(*mathBlockNode)
+	if !n.closed {
+		n.err = fmt.Errorf("$$ without a closing $$")
+	}
+}
+
+func (p *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// mathReference matches math that is nothing but a reference to an equation, which is turned into a link.
+var mathReference = regexp.MustCompile(`^\s*\\(eq)?ref\s*\{([^}]*)\}\s*$`)
+
+// mathTransformer converts the TeX of every equation in a document to MathML. Display equations on lines of their
+// own are numbered in order, unless they have a \tag of their own or are marked with \nonumber, and labelled ones
+// may be referred to from anywhere in the document.
+type mathTransformer struct{}
+
+func (t mathTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	equations := make([]*mathContent, 0)
+	numbered := make(map[*mathContent]bool)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch m := n.(type) {
+		case *mathNode:
+			equations = append(equations, &m.mathContent)
+		case *mathBlockNode:
+			equations = append(equations, &m.mathContent)
+			numbered[&m.mathContent] = true
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(equations) == 0 {
+		return
+	}
+	labels := make(map[string]string)
+	count := 0
+	for _, m := range equations {
+		if m.err != nil {
+			continue
+		}
+		if m.result, m.err = convertTeX(m.tex, nil); m.err != nil {
+			continue
+		}
+		if numbered[m] && !m.result.NoTag {
+			if m.result.Tag != "" {
+				m.number = m.result.Tag
+			} else {
+				count++
+				m.number = strconv.Itoa(count)
+			}
+		}
+		if label := m.result.Label; label != "" {
+			if _, ok := labels[label]; ok {
+				m.err = fmt.Errorf("the label %s is used more than once", label)
+			} else if m.number == "" {
+				m.err = fmt.Errorf(`\label{%s} is on an equation without a number`, label)
+			} else {
+				labels[label] = m.number
+			}
+		}
+	}
+	refs := func(label string) (string, error) {
+		number, ok := labels[label]
+		if !ok {
+			return "", fmt.Errorf("no equation is labelled %s", label)
+		}
+		return number, nil
+	}
+	for _, m := range equations {
+		if m.err == nil {
+			if match := mathReference.FindStringSubmatch(m.tex); match != nil && !m.display {
+				m.ref = strings.TrimSpace(match[2])
+				m.eqref = match[1] != ""
+				m.refTo, m.err = refs(m.ref)
+			} else {
+				m.result, m.err = convertTeX(m.tex, refs)
+			}
+		}
+		if m.err != nil {
+			log.Printf("WARN: could not render the math %q: %s\n", strings.TrimSpace(m.tex), m.err.Error())
+		}
+	}
+}
+
+// equationID is the id of the element holding an equation with the given label.
+func equationID(label string) string {
+	return "eq-" + strings.Join(strings.Fields(label), "-")
+}
+
+type MathRenderer struct{}
+
+// NewMathRenderer returns a new MathRenderer.
+func NewMathRenderer() renderer.NodeRenderer {
+	return &MathRenderer{}
+}
+
+// RegisterFuncs registers the renderer with the Goldmark renderer.
+func (r *MathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMath, r.renderMath)
+	reg.Register(KindMathBlock, r.renderMathBlock)
+}
+
+// writeMath writes an equation as a math element, keeping its TeX as an annotation.
+func writeMath(w util.BufWriter, m *mathContent) {
+	if m.display {
+		_, _ = w.WriteString(`<math display="block">`)
+	} else {
+		_, _ = w.WriteString("<math>")
+	}
+	_, _ = w.WriteString("<semantics>")
+	_, _ = w.WriteString(m.result.MathML)
+	_, _ = w.WriteString(`<annotation encoding="application/x-tex">`)
+	_, _ = w.WriteString(html.EscapeString(strings.TrimSpace(m.tex)))
+	_, _ = w.WriteString("</annotation></semantics></math>")
+}
+
+func (r *MathRenderer) renderMath(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n, ok := node.(*mathNode)
+	if !ok || !entering {
+		return ast.WalkContinue, nil
+	}
+	switch {
+	case n.err != nil:
+		_, _ = w.WriteString(`<code class="math-error" title="`)
+		_, _ = w.WriteString(html.EscapeString(n.err.Error()))
+		_, _ = w.WriteString(`">`)
+		_, _ = w.WriteString(html.EscapeString(n.tex))
+		_, _ = w.WriteString("</code>")
+	case n.ref != "":
+		number := n.refTo
+		if n.eqref {
+			number = "(" + number + ")"
+		}
+		_, _ = w.WriteString(`<a class="equation-ref" href="#`)
+		_, _ = w.WriteString(html.EscapeString(equationID(n.ref)))
+		_, _ = w.WriteString(`">`)
+		_, _ = w.WriteString(html.EscapeString(number))
+		_, _ = w.WriteString("</a>")
+	default:
+		writeMath(w, &n.mathContent)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *MathRenderer) renderMathBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n, ok := node.(*mathBlockNode)
+	if !ok || !entering {
+		return ast.WalkContinue, nil
+	}
+	if n.err != nil {
+		_, _ = w.WriteString(`<div class="math-error"><p>`)
+		_, _ = w.WriteString(html.EscapeString(n.err.Error()))
+		_, _ = w.WriteString("</p><pre>")
+		_, _ = w.WriteString(html.EscapeString(strings.TrimSpace(n.tex)))
+		_, _ = w.WriteString("</pre></div>\n")
+		return ast.WalkSkipChildren, nil
+	}
+	_, _ = w.WriteString(`<div class="equation"`)
+	if n.result.Label != "" {
+		_, _ = w.WriteString(` id="`)
+		_, _ = w.WriteString(html.EscapeString(equationID(n.result.Label)))
+		_, _ = w.WriteString(`"`)
+	}
+	_ = w.WriteByte('>')
+	writeMath(w, &n.mathContent)
+	if n.number != "" {
+		_, _ = w.WriteString(`<span class="equation-number">(`)
+		_, _ = w.WriteString(html.EscapeString(n.number))
+		_, _ = w.WriteString(")</span>")
+	}
+	_, _ = w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+type mathExtension struct{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&mathBlockParser{}, priorityMathBlockParser),
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(&mathParser{}, priorityMathParser),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(mathTransformer{}, priorityMathTransformer),
+		),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(NewMathRenderer(), priorityMathRenderer),
+		),
+	)
+}
+
+// MathExtension renders $inline$ and $$display$$ TeX math as MathML when the page is built, so that no script is
+// needed to show it.
+func MathExtension() goldmark.Extender {
+	return &mathExtension{}
+}
@@ -0,0 +1,896 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package extensions
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// texResult is a TeX expression converted to MathML, along with what it says about its own numbering.
+type texResult struct {
+	MathML string
+	Label  string // The label given with \label, if any
+	Tag    string // The number given with \tag, if any
+	NoTag  bool   // Set by \nonumber or \notag
+}
+
+// texConverter turns a subset of TeX math into MathML. refs finds what \ref and \eqref print for a label. If it is
+// nil, references are left unresolved, so that labels can be collected from every equation before any are resolved.
+type texConverter struct {
+	src     []rune
+	pos     int
+	variant string // The alphabet letters and digits are written in, set by \mathbb and friends
+	refs    func(label string) (string, error)
+	result  texResult
+}
+
+// convertTeX converts the TeX math src to the contents of a math element.
+func convertTeX(src string, refs func(label string) (string, error)) (texResult, error) {
+	c := &texConverter{src: []rune(src), refs: refs}
+	items, err := c.row()
+	if err != nil {
+		return texResult{}, err
+	}
+	if !c.eof() {
+		switch {
+		case c.peek() == '}':
+			return texResult{}, fmt.Errorf("unexpected }")
+		case c.peek() == '&':
+			return texResult{}, fmt.Errorf("& may only be used in an environment such as aligned")
+		case c.atCommand(`\`):
+			return texResult{}, fmt.Errorf(`\\ may only be used in an environment such as aligned`)
+		case c.atCommand("right"):
+			return texResult{}, fmt.Errorf(`\right without a matching \left`)
+		default:
+			return texResult{}, fmt.Errorf(`\end without a matching \begin`)
+		}
+	}
+	c.result.MathML = mrow(items)
+	return c.result, nil
+}
+
+// texItem is a piece of MathML that sub- and superscripts attach to.
+type texItem struct {
+	ml     string
+	after  string // Written after the scripts, such as the invisible function application following \sin
+	limits bool   // Scripts are placed under and over rather than beside
+}
+
+func mrow(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return "<mrow>" + strings.Join(items, "") + "</mrow>"
+}
+
+func (c *texConverter) eof() bool {
+	return c.pos >= len(c.src)
+}
+
+func (c *texConverter) peek() rune {
+	if c.eof() {
+		return 0
+	}
+	return c.src[c.pos]
+}
+
+func (c *texConverter) skipSpace() {
+	for !c.eof() {
+		switch {
+		case unicode.IsSpace(c.peek()):
+			c.pos++
+		case c.peek() == '%':
+			for !c.eof() && c.peek() != '\n' {
+				c.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// atCommand reports whether the next thing in the source is the command name, without consuming it.
+func (c *texConverter) atCommand(name string) bool {
+	if c.peek() != '\\' {
+		return false
+	}
+	save := c.pos
+	defer func() { c.pos = save }()
+	c.pos++
+	return c.commandName() == name
+}
+
+// commandName reads the name of a command after its backslash: a run of letters, or a single other character.
+func (c *texConverter) commandName() string {
+	start := c.pos
+	for !c.eof() && isASCIILetter(c.peek()) {
+		c.pos++
+	}
+	if c.pos == start && !c.eof() {
+		c.pos++
+	}
+	return string(c.src[start:c.pos])
+}
+
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// row reads items until the end of the source or of a group, cell, or row. What ended it is left unread.
+func (c *texConverter) row() ([]string, error) {
+	out := make([]string, 0)
+	for {
+		c.skipSpace()
+		if c.eof() || c.peek() == '}' || c.peek() == '&' {
+			return out, nil
+		}
+		if c.atCommand(`\`) || c.atCommand("end") || c.atCommand("right") {
+			return out, nil
+		}
+		if c.atCommand("displaystyle") || c.atCommand("textstyle") {
+			c.pos++
+			display := c.commandName() == "displaystyle"
+			rest, err := c.row()
+			if err != nil {
+				return nil, err
+			}
+			return append(out, fmt.Sprintf(`<mstyle displaystyle="%t">%s</mstyle>`, display, mrow(rest))), nil
+		}
+		item, ok, err := c.atom()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		ml, err := c.scripts(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ml)
+	}
+}
+
+// scripts attaches any sub- and superscripts following an item to it.
+func (c *texConverter) scripts(base texItem) (string, error) {
+	var sub, sup string
+	var hasSub, hasSup bool
+	primes := ""
+	for {
+		c.skipSpace()
+		switch c.peek() {
+		case '\'':
+			c.pos++
+			primes += "′"
+			continue
+		case '^', '_':
+		default:
+			if primes != "" {
+				if hasSup {
+					sup = "<mrow><mo>" + primes + "</mo>" + sup + "</mrow>"
+				} else {
+					sup = "<mo>" + primes + "</mo>"
+				}
+				hasSup = true
+			}
+			return attachScripts(base, sub, hasSub, sup, hasSup), nil
+		}
+		mark := c.peek()
+		c.pos++
+		arg, err := c.argument()
+		if err != nil {
+			return "", err
+		}
+		if mark == '_' {
+			if hasSub {
+				return "", fmt.Errorf("double subscript")
+			}
+			sub, hasSub = arg, true
+		} else {
+			if hasSup {
+				return "", fmt.Errorf("double superscript")
+			}
+			sup, hasSup = arg, true
+		}
+	}
+}
+
+func attachScripts(base texItem, sub string, hasSub bool, sup string, hasSup bool) string {
+	under, over := "msub", "msup"
+	both := "msubsup"
+	if base.limits {
+		under, over, both = "munder", "mover", "munderover"
+	}
+	out := base.ml
+	switch {
+	case hasSub && hasSup:
+		out = fmt.Sprintf("<%s>%s%s%s</%s>", both, base.ml, sub, sup, both)
+	case hasSub:
+		out = fmt.Sprintf("<%s>%s%s</%s>", under, base.ml, sub, under)
+	case hasSup:
+		out = fmt.Sprintf("<%s>%s%s</%s>", over, base.ml, sup, over)
+	}
+	return out + base.after
+}
+
+// argument reads the argument of a command or script: a group in braces, or a single item.
+func (c *texConverter) argument() (string, error) {
+	c.skipSpace()
+	if c.eof() {
+		return "", fmt.Errorf("missing argument")
+	}
+	if c.peek() == '{' {
+		return c.group()
+	}
+	// Outside of braces, an argument is a single character, so \frac12 is a half.
+	if unicode.IsDigit(c.peek()) {
+		c.pos++
+		return c.number(string(c.src[c.pos-1])), nil
+	}
+	item, ok, err := c.atom()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "<mrow></mrow>", nil
+	}
+	return item.ml + item.after, nil
+}
+
+// group reads a group in braces.
+func (c *texConverter) group() (string, error) {
+	if c.peek() != '{' {
+		return "", fmt.Errorf("expected {")
+	}
+	c.pos++
+	items, err := c.row()
+	if err != nil {
+		return "", err
+	}
+	if c.peek() != '}' {
+		return "", fmt.Errorf("missing }")
+	}
+	c.pos++
+	if len(items) == 0 {
+		return "<mrow></mrow>", nil
+	}
+	return mrow(items), nil
+}
+
+// rawGroup reads a group in braces as plain text, as for \text or \label.
+func (c *texConverter) rawGroup() (string, error) {
+	c.skipSpace()
+	if c.peek() != '{' {
+		return "", fmt.Errorf("expected {")
+	}
+	depth := 0
+	start := c.pos + 1
+	for !c.eof() {
+		switch c.peek() {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				c.pos++
+				return string(c.src[start : c.pos-1]), nil
+			}
+		case '\\':
+			c.pos++
+		}
+		c.pos++
+	}
+	return "", fmt.Errorf("missing }")
+}
+
+// optional reads an optional argument in brackets, if there is one.
+func (c *texConverter) optional() (string, bool, error) {
+	c.skipSpace()
+	if c.peek() != '[' {
+		return "", false, nil
+	}
+	c.pos++
+	start := c.pos
+	for !c.eof() && c.peek() != ']' {
+		c.pos++
+	}
+	if c.eof() {
+		return "", false, fmt.Errorf("missing ]")
+	}
+	c.pos++
+	return string(c.src[start : c.pos-1]), true, nil
+}
+
+// atom reads a single item. ok is false for things that produce no output, such as \label.
+func (c *texConverter) atom() (item texItem, ok bool, err error) {
+	r := c.peek()
+	switch {
+	case r == '{':
+		ml, err := c.group()
+		return texItem{ml: ml}, true, err
+	case r == '\\':
+		c.pos++
+		return c.command(c.commandName())
+	case r == '^' || r == '_':
+		// A script with nothing before it attaches to an empty base.
+		return texItem{ml: "<mrow></mrow>"}, true, nil
+	case unicode.IsDigit(r) || r == '.' && c.pos+1 < len(c.src) && unicode.IsDigit(c.src[c.pos+1]):
+		start := c.pos
+		for !c.eof() && (unicode.IsDigit(c.peek()) || c.peek() == '.') {
+			c.pos++
+		}
+		return texItem{ml: c.number(string(c.src[start:c.pos]))}, true, nil
+	case unicode.IsLetter(r):
+		if c.variant == "normal" {
+			start := c.pos
+			for !c.eof() && unicode.IsLetter(c.peek()) {
+				c.pos++
+			}
+			return texItem{ml: `<mi mathvariant="normal">` + html.EscapeString(string(c.src[start:c.pos])) + "</mi>"}, true, nil
+		}
+		c.pos++
+		return texItem{ml: "<mi>" + html.EscapeString(string(mathAlphabet(c.variant, r))) + "</mi>"}, true, nil
+	case r == '~':
+		c.pos++
+		return texItem{ml: `<mspace width="0.333em"></mspace>`}, true, nil
+	case r == '$' || r == '#':
+		return texItem{}, false, fmt.Errorf("unexpected %c", r)
+	}
+	c.pos++
+	switch r {
+	case '-':
+		return texItem{ml: "<mo>−</mo>"}, true, nil
+	case '*':
+		return texItem{ml: "<mo>∗</mo>"}, true, nil
+	case '(', ')', '[', ']':
+		return texItem{ml: `<mo stretchy="false">` + string(r) + "</mo>"}, true, nil
+	}
+	return texItem{ml: "<mo>" + html.EscapeString(string(r)) + "</mo>"}, true, nil
+}
+
+func (c *texConverter) number(digits string) string {
+	if c.variant == "" || c.variant == "normal" {
+		return "<mn>" + digits + "</mn>"
+	}
+	out := []rune(digits)
+	for i, r := range out {
+		out[i] = mathAlphabet(c.variant, r)
+	}
+	return "<mn>" + string(out) + "</mn>"
+}
+
+// command reads what follows a command with the given name.
+func (c *texConverter) command(name string) (texItem, bool, error) {
+	if s, ok := texIdentifiers[name]; ok {
+		return texItem{ml: "<mi>" + s + "</mi>"}, true, nil
+	}
+	if s, ok := texUprightIdentifiers[name]; ok {
+		return texItem{ml: `<mi mathvariant="normal">` + s + "</mi>"}, true, nil
+	}
+	if s, ok := texOperators[name]; ok {
+		return texItem{ml: "<mo>" + s + "</mo>"}, true, nil
+	}
+	if s, ok := texDelimiters[name]; ok {
+		return texItem{ml: `<mo stretchy="false">` + s + "</mo>"}, true, nil
+	}
+	if op, ok := texLargeOperators[name]; ok {
+		limits := op.limits
+		c.skipSpace()
+		if c.atCommand("limits") || c.atCommand("nolimits") {
+			c.pos++
+			limits = c.commandName() == "limits"
+		}
+		return texItem{ml: `<mo largeop="true" movablelimits="` + fmt.Sprint(op.limits) + `">` + op.symbol + "</mo>", limits: limits}, true, nil
+	}
+	if limits, ok := texFunctions[name]; ok {
+		return texItem{ml: "<mi>" + name + "</mi>", after: "<mo>⁡</mo>", limits: limits}, true, nil
+	}
+	if width, ok := texSpaces[name]; ok {
+		return texItem{ml: `<mspace width="` + width + `"></mspace>`}, true, nil
+	}
+	if accent, ok := texAccents[name]; ok {
+		arg, err := c.argument()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		tag := "mover"
+		attr := `accent="true"`
+		if name == "underline" || name == "underbrace" {
+			tag = "munder"
+			attr = `accentunder="true"`
+		}
+		return texItem{ml: fmt.Sprintf(`<%s %s>%s<mo stretchy="%t">%s</mo></%s>`, tag, attr, arg, accent.stretchy, accent.symbol, tag), limits: name == "overbrace" || name == "underbrace"}, true, nil
+	}
+	if variant, ok := texAlphabets[name]; ok {
+		c.skipSpace()
+		save := c.variant
+		c.variant = variant
+		arg, err := c.argument()
+		c.variant = save
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		return texItem{ml: arg}, true, nil
+	}
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac", "binom", "dbinom", "tbinom":
+		num, err := c.argument()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		den, err := c.argument()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		frac := "<mfrac>" + num + den + "</mfrac>"
+		if strings.HasSuffix(name, "binom") {
+			frac = `<mrow><mo>(</mo><mfrac linethickness="0">` + num + den + `</mfrac><mo>)</mo></mrow>`
+		}
+		switch name[0] {
+		case 'd', 'c':
+			frac = `<mstyle displaystyle="true">` + frac + "</mstyle>"
+		case 't':
+			frac = `<mstyle displaystyle="false">` + frac + "</mstyle>"
+		}
+		return texItem{ml: frac}, true, nil
+	case "sqrt":
+		index, hasIndex, err := c.optional()
+		if err != nil {
+			return texItem{}, false, err
+		}
+		arg, err := c.argument()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\sqrt: %w`, err)
+		}
+		if !hasIndex {
+			return texItem{ml: "<msqrt>" + arg + "</msqrt>"}, true, nil
+		}
+		root, err := convertTeX(index, c.refs)
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\sqrt: %w`, err)
+		}
+		return texItem{ml: "<mroot>" + arg + root.MathML + "</mroot>"}, true, nil
+	case "overset", "underset", "stackrel":
+		top, err := c.argument()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		base, err := c.argument()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		if name == "underset" {
+			return texItem{ml: "<munder>" + base + top + "</munder>"}, true, nil
+		}
+		return texItem{ml: "<mover>" + base + top + "</mover>"}, true, nil
+	case "text", "textrm", "textit", "textbf", "mbox":
+		text, err := c.rawGroup()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		return texItem{ml: "<mtext>" + html.EscapeString(unescapeText(text)) + "</mtext>"}, true, nil
+	case "operatorname":
+		text, err := c.rawGroup()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\operatorname: %w`, err)
+		}
+		return texItem{ml: "<mi>" + html.EscapeString(text) + "</mi>", after: "<mo>⁡</mo>"}, true, nil
+	case "not":
+		c.skipSpace()
+		item, ok, err := c.atom()
+		if err != nil || !ok {
+			return texItem{}, false, fmt.Errorf(`\not must be followed by a relation`)
+		}
+		return texItem{ml: strings.Replace(item.ml, "</mo>", "̸</mo>", 1)}, true, nil
+	case "left":
+		return c.fenced()
+	case "middle":
+		delim, err := c.delimiter()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\middle: %w`, err)
+		}
+		return texItem{ml: `<mo stretchy="true" fence="true">` + delim + "</mo>"}, true, nil
+	case "big", "Big", "bigg", "Bigg", "bigl", "Bigl", "biggl", "Biggl", "bigr", "Bigr", "biggr", "Biggr", "bigm", "Bigm", "biggm", "Biggm":
+		delim, err := c.delimiter()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		size := texDelimiterSizes[strings.TrimRight(name, "lrm")]
+		return texItem{ml: fmt.Sprintf(`<mo minsize="%s" maxsize="%s">%s</mo>`, size, size, delim)}, true, nil
+	case "begin":
+		return c.environment()
+	case "label":
+		label, err := c.rawGroup()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\label: %w`, err)
+		}
+		if c.result.Label != "" {
+			return texItem{}, false, fmt.Errorf(`an equation may only have one \label`)
+		}
+		c.result.Label = strings.TrimSpace(label)
+		return texItem{}, false, nil
+	case "tag":
+		tag, err := c.rawGroup()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\tag: %w`, err)
+		}
+		c.result.Tag = strings.TrimSpace(tag)
+		return texItem{}, false, nil
+	case "nonumber", "notag":
+		c.result.NoTag = true
+		return texItem{}, false, nil
+	case "ref", "eqref":
+		label, err := c.rawGroup()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\%s: %w`, name, err)
+		}
+		number := "?"
+		if c.refs != nil {
+			if number, err = c.refs(strings.TrimSpace(label)); err != nil {
+				return texItem{}, false, err
+			}
+		}
+		if name == "eqref" {
+			number = "(" + number + ")"
+		}
+		return texItem{ml: "<mtext>" + html.EscapeString(number) + "</mtext>"}, true, nil
+	case "{", "}", "%", "$", "#", "&", "_":
+		return texItem{ml: "<mo>" + html.EscapeString(name) + "</mo>"}, true, nil
+	case "pmod":
+		arg, err := c.argument()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf(`\pmod: %w`, err)
+		}
+		return texItem{ml: `<mrow><mspace width="1em"></mspace><mo stretchy="false">(</mo><mi>mod</mi><mspace width="0.333em"></mspace>` + arg + `<mo stretchy="false">)</mo></mrow>`}, true, nil
+	case "bmod", "mod":
+		return texItem{ml: "<mo>mod</mo>"}, true, nil
+	}
+	if name == "" {
+		return texItem{}, false, fmt.Errorf(`a lone \ at the end`)
+	}
+	return texItem{}, false, fmt.Errorf(`unsupported macro \%s`, name)
+}
+
+// unescapeText removes the backslashes of escaped characters in text.
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\{`, "{", `\}`, "}", `\%`, "%", `\$`, "$", `\&`, "&", `\_`, "_", `\#`, "#").Replace(s)
+}
+
+// delimiter reads the delimiter following \left, \right, \middle, or \big. A period stands for no delimiter.
+func (c *texConverter) delimiter() (string, error) {
+	c.skipSpace()
+	if c.eof() {
+		return "", fmt.Errorf("missing delimiter")
+	}
+	r := c.peek()
+	c.pos++
+	switch r {
+	case '.':
+		return "", nil
+	case '(', ')', '[', ']', '|', '/':
+		return string(r), nil
+	case '<':
+		return "⟨", nil
+	case '>':
+		return "⟩", nil
+	case '\\':
+		name := c.commandName()
+		if s, ok := texDelimiters[name]; ok {
+			return s, nil
+		}
+		switch name {
+		case "{", "}", "|":
+			return map[string]string{"{": "{", "}": "}", "|": "‖"}[name], nil
+		}
+		return "", fmt.Errorf(`\%s is not a delimiter`, name)
+	}
+	return "", fmt.Errorf("%c is not a delimiter", r)
+}
+
+// fenced reads everything from \left up to its \right.
+func (c *texConverter) fenced() (texItem, bool, error) {
+	open, err := c.delimiter()
+	if err != nil {
+		return texItem{}, false, fmt.Errorf(`\left: %w`, err)
+	}
+	items, err := c.row()
+	if err != nil {
+		return texItem{}, false, err
+	}
+	if !c.atCommand("right") {
+		return texItem{}, false, fmt.Errorf(`\left without a matching \right`)
+	}
+	c.pos++
+	c.commandName()
+	closing, err := c.delimiter()
+	if err != nil {
+		return texItem{}, false, fmt.Errorf(`\right: %w`, err)
+	}
+	out := "<mrow>"
+	if open != "" {
+		out += `<mo fence="true" form="prefix" stretchy="true">` + html.EscapeString(open) + "</mo>"
+	}
+	out += strings.Join(items, "")
+	if closing != "" {
+		out += `<mo fence="true" form="postfix" stretchy="true">` + html.EscapeString(closing) + "</mo>"
+	}
+	return texItem{ml: out + "</mrow>"}, true, nil
+}
+
+// texEnvironment describes how an environment is laid out as a table.
+type texEnvironment struct {
+	open, close string // Delimiters around the table
+	align       string // The columnalign of the table, repeated as needed
+	spacing     string
+	display     bool
+}
+
+var texEnvironments = map[string]texEnvironment{
+	"matrix":   {},
+	"pmatrix":  {open: "(", close: ")"},
+	"bmatrix":  {open: "[", close: "]"},
+	"Bmatrix":  {open: "{", close: "}"},
+	"vmatrix":  {open: "|", close: "|"},
+	"Vmatrix":  {open: "‖", close: "‖"},
+	"cases":    {open: "{", align: "left left", spacing: "1em"},
+	"aligned":  {align: "right left", spacing: "0em 2em", display: true},
+	"align":    {align: "right left", spacing: "0em 2em", display: true},
+	"align*":   {align: "right left", spacing: "0em 2em", display: true},
+	"split":    {align: "right left", spacing: "0em 2em", display: true},
+	"gathered": {display: true},
+	"gather":   {display: true},
+	"array":    {},
+}
+
+// environment reads an environment from after its \begin up to and including its \end.
+func (c *texConverter) environment() (texItem, bool, error) {
+	name, err := c.rawGroup()
+	if err != nil {
+		return texItem{}, false, fmt.Errorf(`\begin: %w`, err)
+	}
+	env, ok := texEnvironments[name]
+	if !ok {
+		return texItem{}, false, fmt.Errorf("unsupported environment %s", name)
+	}
+	if name == "array" {
+		spec, err := c.rawGroup()
+		if err != nil {
+			return texItem{}, false, fmt.Errorf("array: %w", err)
+		}
+		columns := make([]string, 0)
+		for _, r := range spec {
+			switch r {
+			case 'l':
+				columns = append(columns, "left")
+			case 'c':
+				columns = append(columns, "center")
+			case 'r':
+				columns = append(columns, "right")
+			}
+		}
+		env.align = strings.Join(columns, " ")
+	}
+	rows := make([]string, 0)
+	cells := make([]string, 0)
+	for {
+		items, err := c.row()
+		if err != nil {
+			return texItem{}, false, err
+		}
+		cells = append(cells, "<mtd>"+mrow(items)+"</mtd>")
+		switch {
+		case c.peek() == '&':
+			c.pos++
+			continue
+		case c.atCommand(`\`):
+			c.pos += 2
+			if _, _, err := c.optional(); err != nil {
+				return texItem{}, false, err
+			}
+			rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+			cells = cells[:0]
+			continue
+		case c.atCommand("end"):
+			c.pos++
+			c.commandName()
+			end, err := c.rawGroup()
+			if err != nil {
+				return texItem{}, false, fmt.Errorf(`\end: %w`, err)
+			}
+			if end != name {
+				return texItem{}, false, fmt.Errorf(`\begin{%s} ended by \end{%s}`, name, end)
+			}
+		case c.eof():
+			return texItem{}, false, fmt.Errorf(`\begin{%s} without a matching \end`, name)
+		default:
+			return texItem{}, false, fmt.Errorf("unexpected %c in %s", c.peek(), name)
+		}
+		break
+	}
+	// A trailing \\ leaves an empty row behind, which is dropped.
+	if len(cells) > 1 || len(cells) == 1 && cells[0] != "<mtd><mrow></mrow></mtd>" {
+		rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+	}
+	attrs := ""
+	if env.align != "" {
+		attrs += ` columnalign="` + env.align + `"`
+	}
+	if env.spacing != "" {
+		attrs += ` columnspacing="` + env.spacing + `"`
+	}
+	if env.display {
+		attrs += ` displaystyle="true"`
+	}
+	out := "<mtable" + attrs + ">" + strings.Join(rows, "") + "</mtable>"
+	if env.open != "" || env.close != "" {
+		out = `<mrow><mo fence="true" form="prefix" stretchy="true">` + env.open + "</mo>" + out
+		if env.close != "" {
+			out += `<mo fence="true" form="postfix" stretchy="true">` + env.close + "</mo>"
+		}
+		out += "</mrow>"
+	}
+	return texItem{ml: out}, true, nil
+}
+
+var texIdentifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε", "zeta": "ζ",
+	"eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν",
+	"xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ",
+	"upsilon": "υ", "phi": "ϕ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅", "varnothing": "∅", "hbar": "ℏ", "ell": "ℓ",
+	"Re": "ℜ", "Im": "ℑ", "aleph": "ℵ", "wp": "℘", "imath": "ı", "jmath": "ȷ",
+}
+
+// texUprightIdentifiers are letters that TeX sets upright, unlike other single letters.
+var texUprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π", "Sigma": "Σ",
+	"Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+}
+
+var texOperators = map[string]string{
+	"cdot": "⋅", "times": "×", "div": "÷", "pm": "±", "mp": "∓", "ast": "∗", "star": "⋆", "circ": "∘",
+	"bullet": "∙", "oplus": "⊕", "ominus": "⊖", "otimes": "⊗", "odot": "⊙", "setminus": "∖",
+	"cup": "∪", "cap": "∩", "wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"le": "≤", "leq": "≤", "ge": "≥", "geq": "≥", "ne": "≠", "neq": "≠", "ll": "≪", "gg": "≫",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝", "doteq": "≐",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆", "supset": "⊃", "supseteq": "⊇",
+	"mid": "∣", "parallel": "∥", "perp": "⊥", "forall": "∀", "exists": "∃", "nexists": "∄", "angle": "∠",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔", "mapsto": "↦",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "impliedby": "⟸",
+	"iff": "⟺", "longrightarrow": "⟶", "longleftarrow": "⟵", "longmapsto": "⟼", "uparrow": "↑",
+	"downarrow": "↓", "hookrightarrow": "↪", "nearrow": "↗", "searrow": "↘",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱", "prime": "′", "colon": ":",
+	"triangle": "△", "square": "□", "therefore": "∴", "because": "∵", "vdash": "⊢", "models": "⊨",
+}
+
+var texDelimiters = map[string]string{
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"lvert": "|", "rvert": "|", "vert": "|", "lVert": "‖", "rVert": "‖", "Vert": "‖", "|": "‖",
+	"lbrace": "{", "rbrace": "}", "backslash": "\\",
+}
+
+// texDelimiterSizes are the sizes \big and its relatives give delimiters.
+var texDelimiterSizes = map[string]string{
+	"big": "1.2em", "Big": "1.623em", "bigg": "2.047em", "Bigg": "2.470em",
+}
+
+type texLargeOperator struct {
+	symbol string
+	limits bool // Scripts go under and over the operator in display style
+}
+
+var texLargeOperators = map[string]texLargeOperator{
+	"sum": {"∑", true}, "prod": {"∏", true}, "coprod": {"∐", true}, "bigcup": {"⋃", true},
+	"bigcap": {"⋂", true}, "bigoplus": {"⨁", true}, "bigotimes": {"⨂", true}, "bigvee": {"⋁", true},
+	"bigwedge": {"⋀", true}, "int": {"∫", false}, "iint": {"∬", false}, "iiint": {"∭", false},
+	"oint": {"∮", false},
+}
+
+// texFunctions are the named functions, and whether their scripts go under and over them.
+var texFunctions = map[string]bool{
+	"sin": false, "cos": false, "tan": false, "cot": false, "sec": false, "csc": false, "arcsin": false,
+	"arccos": false, "arctan": false, "sinh": false, "cosh": false, "tanh": false, "coth": false, "log": false,
+	"ln": false, "lg": false, "exp": false, "ker": false, "dim": false, "deg": false, "arg": false, "hom": false,
+	"lim": true, "limsup": true, "liminf": true, "max": true, "min": true, "sup": true, "inf": true,
+	"det": true, "gcd": true, "Pr": true,
+}
+
+var texSpaces = map[string]string{
+	",": "0.167em", ":": "0.222em", ">": "0.222em", ";": "0.278em", "!": "-0.167em", " ": "0.333em",
+	"quad": "1em", "qquad": "2em",
+}
+
+type texAccent struct {
+	symbol   string
+	stretchy bool
+}
+
+var texAccents = map[string]texAccent{
+	"hat": {"^", false}, "widehat": {"^", true}, "check": {"ˇ", false}, "tilde": {"~", false},
+	"widetilde": {"~", true}, "acute": {"´", false}, "grave": {"`", false}, "dot": {"˙", false},
+	"ddot": {"¨", false}, "breve": {"˘", false}, "bar": {"¯", false}, "vec": {"→", false},
+	"overline": {"‾", true}, "underline": {"_", true}, "overrightarrow": {"→", true},
+	"overleftarrow": {"←", true}, "overbrace": {"⏞", true}, "underbrace": {"⏟", true},
+}
+
+// texAlphabets are the commands that set letters in another alphabet, and the alphabet each uses.
+var texAlphabets = map[string]string{
+	"mathrm": "normal", "mathbf": "bold", "boldsymbol": "bold", "mathit": "italic", "mathsf": "sans-serif",
+	"mathtt": "monospace", "mathcal": "script", "mathscr": "script", "mathbb": "double-struck",
+	"mathfrak": "fraktur",
+}
+
+// mathAlphabetStarts gives where the capital letters, small letters, and digits of each alphabet begin in the
+// Mathematical Alphanumeric Symbols block. Alphabets without digits have zero for them.
+var mathAlphabetStarts = map[string][3]rune{
+	"bold":          {0x1D400, 0x1D41A, 0x1D7CE},
+	"italic":        {0x1D434, 0x1D44E, 0},
+	"script":        {0x1D49C, 0x1D4B6, 0},
+	"fraktur":       {0x1D504, 0x1D51E, 0},
+	"double-struck": {0x1D538, 0x1D552, 0x1D7D8},
+	"sans-serif":    {0x1D5A0, 0x1D5BA, 0x1D7E2},
+	"monospace":     {0x1D670, 0x1D68A, 0x1D7F6},
+}
+
+// mathAlphabetHoles are the letters encoded before the block was, which it leaves out.
+var mathAlphabetHoles = map[string]map[rune]rune{
+	"italic": {'h': 'ℎ'},
+	"script": {'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ', 'M': 'ℳ', 'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ',
+		'o': 'ℴ'},
+	"fraktur":       {'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ'},
+	"double-struck": {'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ'},
+}
+
+// mathAlphabet gives the character r in the alphabet variant, or r itself if it has none there.
+func mathAlphabet(variant string, r rune) rune {
+	starts, ok := mathAlphabetStarts[variant]
+	if !ok {
+		return r
+	}
+	if hole, ok := mathAlphabetHoles[variant][r]; ok {
+		return hole
+	}
+	switch {
+	case r >= 'A' && r <= 'Z':
+		return starts[0] + r - 'A'
+	case r >= 'a' && r <= 'z':
+		return starts[1] + r - 'a'
+	case r >= '0' && r <= '9' && starts[2] != 0:
+		return starts[2] + r - '0'
+	}
+	return r
+}
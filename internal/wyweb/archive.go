@@ -0,0 +1,218 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"wyweb.site/util"
+)
+
+// ParseArchivePath splits a request path such as blog/archive/2024/05 into the path of the node being archived and
+// the requested year and month. A year or month of zero means the whole archive or the whole year, respectively.
+func ParseArchivePath(reqPath string) (base string, year int, month int, ok bool) {
+	parts := util.PathToList(reqPath)
+	idx := -1
+	for i, part := range parts {
+		if part == "archive" {
+			idx = i
+		}
+	}
+	if idx < 0 || len(parts)-idx > 3 {
+		return "", 0, 0, false
+	}
+	var err error
+	if len(parts) > idx+1 {
+		year, err = strconv.Atoi(parts[idx+1])
+		if err != nil || year < 1 {
+			return "", 0, 0, false
+		}
+	}
+	if len(parts) > idx+2 {
+		month, err = strconv.Atoi(parts[idx+2])
+		if err != nil || month < 1 || month > 12 {
+			return "", 0, 0, false
+		}
+	}
+	return path.Join(parts[:idx]...), year, month, true
+}
+
+// collectDated gathers every post and gallery item beneath node that has a publication date.
+func collectDated(node *ConfigNode) []Listable {
+	out := make([]Listable, 0)
+	var dft func(*ConfigNode)
+	dft = func(n *ConfigNode) {
+		switch n.NodeKind {
+		case WWPOST, WWRECIPE:
+			if !n.Date.IsZero() && !n.Draft {
+				out = append(out, n)
+			}
+		case WWGALLERY:
+			for idx := range n.Images {
+				if !n.Images[idx].Date.IsZero() {
+					out = append(out, &n.Images[idx])
+				}
+			}
+		}
+		for _, child := range n.Children {
+			dft(child)
+		}
+	}
+	dft(node)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].GetDate().After(out[j].GetDate())
+	})
+	return out
+}
+
+func archivePath(node *ConfigNode, year int, month int) string {
+	out := "/" + path.Join(node.Path, "archive")
+	if year > 0 {
+		out += fmt.Sprintf("/%d", year)
+	}
+	if month > 0 {
+		out += fmt.Sprintf("/%02d", month)
+	}
+	return out
+}
+
+// ArchiveCrumbs returns the extra breadcrumbs leading from node to the requested archive page.
+func ArchiveCrumbs(node *ConfigNode, year int, month int) []WWNavLink {
+	crumbs := []WWNavLink{{Path: archivePath(node, 0, 0), Text: node.T("Archive")}}
+	if year > 0 {
+		crumbs = append(crumbs, WWNavLink{Path: archivePath(node, year, 0), Text: strconv.Itoa(year)})
+	}
+	if month > 0 {
+		crumbs = append(crumbs, WWNavLink{Path: archivePath(node, year, month), Text: node.monthName(time.Month(month), false)})
+	}
+	return crumbs
+}
+
+func countNoun(node *ConfigNode, n int) string {
+	if n == 1 {
+		return node.T("1 item")
+	}
+	return node.T("%d items", n)
+}
+
+// BuildArchive renders the archive of node. With no year, a calendar of every year and month with content is shown.
+// With a year, the items of that year are listed under a heading for each month.
+func BuildArchive(node *ConfigNode, year int, month int, crumbs *HTMLElement) (*HTMLElement, error) {
+	if node.original() != node.Tree.Root && node.NodeKind != WWLISTING && node.NodeKind != WWPODCAST {
+		return nil, fmt.Errorf("%s cannot be archived", node.Path)
+	}
+	all := collectDated(node)
+	byYear := make(map[int][]Listable)
+	for _, item := range all {
+		y := item.GetDate().Year()
+		byYear[y] = append(byYear[y], item)
+	}
+	if year == 0 {
+		body := BuildListing(node, nil, crumbs, node.T("Archive"), countNoun(node, len(all)))
+		container, err := body.FirstElementByClass("listing-container")
+		if err != nil {
+			container = body
+		}
+		container.Append(buildArchiveCalendar(node, byYear))
+		return body, nil
+	}
+	byMonth := make(map[time.Month][]Listable)
+	for _, item := range byYear[year] {
+		m := item.GetDate().Month()
+		byMonth[m] = append(byMonth[m], item)
+	}
+	if month > 0 {
+		items := byMonth[time.Month(month)]
+		if len(items) == 0 {
+			return nil, fmt.Errorf("nothing published in %d-%02d", year, month)
+		}
+		title := node.T("%s %d", node.monthName(time.Month(month), false), year)
+		return BuildListing(node, items, crumbs, title, countNoun(node, len(items))), nil
+	}
+	if len(byYear[year]) == 0 {
+		return nil, fmt.Errorf("nothing published in %d", year)
+	}
+	body := BuildListing(node, nil, crumbs, strconv.Itoa(year), countNoun(node, len(byYear[year])))
+	container, err := body.FirstElementByClass("listing-container")
+	if err != nil {
+		container = body
+	}
+	for m := time.December; m >= time.January; m-- {
+		items := byMonth[m]
+		if len(items) == 0 {
+			continue
+		}
+		section := container.AppendNew("section", Class("archive-month"))
+		heading := section.AppendNew("h2")
+		heading.AppendNew("a", Href(archivePath(node, year, int(m)))).AppendText(node.monthName(m, false))
+		heading.AppendNew("span", Class("archive-count")).AppendText(countNoun(node, len(items)))
+		appendListItems(section, items)
+	}
+	return body, nil
+}
+
+func buildArchiveCalendar(node *ConfigNode, byYear map[int][]Listable) *HTMLElement {
+	calendar := NewHTMLElement("div", Class("archive-calendar"))
+	years := make([]int, 0, len(byYear))
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+	for _, y := range years {
+		counts := make(map[time.Month]int)
+		for _, item := range byYear[y] {
+			counts[item.GetDate().Month()]++
+		}
+		section := calendar.AppendNew("section", Class("archive-year"))
+		heading := section.AppendNew("h2")
+		heading.AppendNew("a", Href(archivePath(node, y, 0))).AppendText(strconv.Itoa(y))
+		heading.AppendNew("span", Class("archive-count")).AppendText(countNoun(node, len(byYear[y])))
+		months := section.AppendNew("ol", Class("archive-months"))
+		for m := time.January; m <= time.December; m++ {
+			abbr := node.monthName(m, true)
+			if counts[m] == 0 {
+				months.AppendNew("li", Class("archive-month archive-empty")).AppendText(abbr)
+				continue
+			}
+			li := months.AppendNew("li", Class("archive-month"))
+			li.AppendNew("a", Href(archivePath(node, y, int(m)))).AppendText(abbr)
+			li.AppendNew("span", Class("archive-count")).AppendText(strconv.Itoa(counts[m]))
+		}
+	}
+	return calendar
+}
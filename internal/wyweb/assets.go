@@ -0,0 +1,232 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Fingerprinted copies of raw resources are written to this directory of the document root. Those of local resources
+// are written next to the file they were made from.
+const assetDir = "_assets"
+
+// The integrity hashes of pinned url resources are kept in this file of the document root, by URL.
+const pinFile = "pinned.yaml"
+
+// Browsers may keep a fingerprinted file forever, since a change to it is published under a new name.
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+// A raw or local resource after minification, and the fingerprinted file it was written to if it asked for one.
+type asset struct {
+	Type    string
+	Content string
+	Source  string    // The file of a local resource, or the value of a raw one
+	ModTime time.Time // When the file of a local resource was last modified
+	URL     string    // The fingerprinted file, as linked from pages. Empty if the resource is placed inline
+	// The Subresource Integrity hash of the fingerprinted file
+	Integrity string
+	stale     bool
+}
+
+func (r Resource) minified() bool {
+	return r.Minify == nil || *r.Minify
+}
+
+func minifyResource(kind string, text string) string {
+	switch kind {
+	case "style":
+		return minifyCSS(text)
+	case "script":
+		return minifyJS(text)
+	}
+	return text
+}
+
+// fingerprintName inserts the first few bytes of the hash of content before the extension of name, e.g.
+// style.3f9a1c.css.
+func fingerprintName(name string, content string) string {
+	sum := sha256.Sum256([]byte(content))
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:3]) + ext
+}
+
+func assetExtension(kind string) string {
+	if kind == "script" {
+		return ".js"
+	}
+	return ".css"
+}
+
+// integrityHash is the value of an integrity attribute that lets browsers check they were given exactly content.
+func integrityHash(content []byte) string {
+	sum := sha512.Sum384(content)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// assetName turns the key of a resource into a file name, since the keys of resources defined further down the site
+// include the path of the node defining them.
+func assetName(key string) string {
+	return strings.NewReplacer("/", "-", ":", "-").Replace(key)
+}
+
+// buildAsset reads, minifies, and, if asked to, fingerprints a raw or local resource.
+func (tree *ConfigTree) buildAsset(name string, res Resource) (*asset, error) {
+	out := &asset{Type: res.Type, Source: res.Value}
+	text := res.Value
+	if res.Method == "local" {
+		st, err := os.Stat(filepath.Join(tree.rootDir, res.Value))
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(tree.rootDir, res.Value))
+		if err != nil {
+			return nil, err
+		}
+		out.ModTime = st.ModTime()
+		text = string(data)
+	}
+	if res.minified() {
+		text = minifyResource(res.Type, text)
+	}
+	out.Content = text
+	if !res.Fingerprint {
+		return out, nil
+	}
+	var filename string
+	if res.Method == "local" {
+		filename = fingerprintName(res.Value, text)
+	} else {
+		filename = filepath.Join(assetDir, fingerprintName(assetName(name)+assetExtension(res.Type), text))
+		if err := os.MkdirAll(filepath.Join(tree.rootDir, assetDir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tree.rootDir, filename), []byte(text), 0644); err != nil {
+		return nil, err
+	}
+	out.URL = "/" + filepath.ToSlash(filename)
+	out.Integrity = integrityHash([]byte(text))
+	return out, nil
+}
+
+// processedResource gives a raw or local resource as it should appear on a page: minified inline, or as a link to its
+// fingerprinted file. Resources are processed once and kept until their file changes.
+func (tree *ConfigTree) processedResource(name string, res Resource) interface{} {
+	tree.assetLock.Lock()
+	defer tree.assetLock.Unlock()
+	cached, ok := tree.assets[name]
+	if !ok || cached.stale || cached.Source != res.Value || (cached.URL != "") != res.Fingerprint {
+		fresh, err := tree.buildAsset(name, res)
+		if err != nil {
+			log.Printf("WARN: could not process the resource %s: %s", name, err.Error())
+			return nil
+		}
+		// The old file is no longer linked from anywhere once pages are given the new one.
+		if ok && cached.URL != "" && cached.URL != fresh.URL {
+			os.Remove(filepath.Join(tree.rootDir, strings.TrimPrefix(cached.URL, "/")))
+		}
+		tree.assets[name] = fresh
+		cached = fresh
+	}
+	if cached.URL != "" {
+		return URLResource{String: cached.URL, Attributes: res.Attributes}
+	}
+	return RawResource{String: cached.Content, Attributes: res.Attributes}
+}
+
+// processResources prepares every raw and local resource known so far, so that their fingerprinted files can be
+// served before any page linking to them has been.
+func (tree *ConfigTree) processResources() {
+	tree.RLock()
+	resources := make(map[string]Resource, len(tree.Resources))
+	for name, res := range tree.Resources {
+		tree.checkLoading(name, res)
+		if tree.mustFingerprint(name, res) {
+			res.Fingerprint = true
+		}
+		resources[name] = res
+	}
+	tree.RUnlock()
+	pins := make([]string, 0)
+	for name, res := range resources {
+		switch {
+		case res.Method == "raw" || res.Method == "local":
+			tree.processedResource(name, res)
+		case res.Method == "url" && res.Pin && res.Integrity == "":
+			pins = append(pins, res.Value)
+		}
+	}
+	if len(pins) > 0 {
+		go tree.pinResources(pins)
+	}
+}
+
+// expireAsset marks the resources made from a file as stale if the file has changed since, so that they are minified
+// and fingerprinted again when next used.
+func (tree *ConfigTree) expireAsset(path string) {
+	st, err := os.Stat(filepath.Join(tree.rootDir, path))
+	if err != nil {
+		return
+	}
+	tree.assetLock.Lock()
+	defer tree.assetLock.Unlock()
+	for _, a := range tree.assets {
+		if a.Source == path && st.ModTime().After(a.ModTime) {
+			a.stale = true
+		}
+	}
+}
+
+// GetAsset finds a fingerprinted file by its path from the document root, along with its content type.
+func (tree *ConfigTree) GetAsset(path string) ([]byte, string, bool) {
+	tree.assetLock.Lock()
+	defer tree.assetLock.Unlock()
+	for _, a := range tree.assets {
+		if a.URL != "" && a.URL == "/"+path {
+			if a.Type == "script" {
+				return []byte(a.Content), "text/javascript; charset=utf-8", true
+			}
+			return []byte(a.Content), "text/css; charset=utf-8", true
+		}
+	}
+	return nil, "", false
+}
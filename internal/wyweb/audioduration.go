@@ -0,0 +1,261 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Bitrates of MPEG audio layer III in kbit/s, by bitrate index, for MPEG-1 and for MPEG-2 and 2.5.
+var mp3Bitrates = [2][16]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+var mp3SampleRates = [3]int{44100, 48000, 32000}
+
+// audioDuration reads the length of an audio file from its headers. MP3, WAV, FLAC, and Ogg (Vorbis or Opus) files
+// are understood.
+func audioDuration(path string) (time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	st, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")) {
+	case "mp3":
+		return mp3Duration(file, st.Size())
+	case "wav":
+		return wavDuration(file)
+	case "flac":
+		return flacDuration(file)
+	case "ogg":
+		return oggDuration(file, st.Size())
+	}
+	return 0, fmt.Errorf("cannot determine the duration of %s", path)
+}
+
+func seconds(count uint64, rate int) time.Duration {
+	return time.Duration(float64(count) / float64(rate) * float64(time.Second))
+}
+
+func mp3Duration(file *os.File, size int64) (time.Duration, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return 0, err
+	}
+	// Skip over any ID3v2 tag, whose size is stored as a syncsafe integer.
+	var start int64
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		start = 10 + (int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9]))
+		if header[5]&0x10 != 0 {
+			start += 10
+		}
+	}
+	buf := make([]byte, 8192)
+	n, err := file.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	buf = buf[:n]
+	idx := 0
+	for ; idx+4 <= len(buf); idx++ {
+		if buf[idx] == 0xFF && buf[idx+1]&0xE0 == 0xE0 {
+			break
+		}
+	}
+	if idx+4 > len(buf) {
+		return 0, fmt.Errorf("no MPEG frame found")
+	}
+	frame := buf[idx:]
+	version := (frame[1] >> 3) & 3 // 3 is MPEG-1, 2 is MPEG-2, 0 is MPEG-2.5
+	layer := (frame[1] >> 1) & 3   // 1 is layer III
+	bitrateIdx := frame[2] >> 4
+	rateIdx := (frame[2] >> 2) & 3
+	mono := frame[3]>>6 == 3
+	if layer != 1 || version == 1 || rateIdx == 3 {
+		return 0, fmt.Errorf("unsupported MPEG audio")
+	}
+	table := 0
+	sampleRate := mp3SampleRates[rateIdx]
+	samplesPerFrame := 1152
+	sideInfo := 32
+	if mono {
+		sideInfo = 17
+	}
+	if version != 3 {
+		table = 1
+		samplesPerFrame = 576
+		sampleRate /= 2
+		sideInfo = 17
+		if mono {
+			sideInfo = 9
+		}
+		if version == 0 {
+			sampleRate /= 2
+		}
+	}
+	// A VBR file announces its number of frames in a Xing (or Info) header or a VBRI header in the first frame.
+	xing := 4 + sideInfo
+	if len(frame) >= xing+12 && (bytes.Equal(frame[xing:xing+4], []byte("Xing")) || bytes.Equal(frame[xing:xing+4], []byte("Info"))) {
+		if flags := binary.BigEndian.Uint32(frame[xing+4:]); flags&1 != 0 {
+			frames := binary.BigEndian.Uint32(frame[xing+8:])
+			return seconds(uint64(frames)*uint64(samplesPerFrame), sampleRate), nil
+		}
+	}
+	if len(frame) >= 36+18 && bytes.Equal(frame[36:40], []byte("VBRI")) {
+		frames := binary.BigEndian.Uint32(frame[36+14:])
+		return seconds(uint64(frames)*uint64(samplesPerFrame), sampleRate), nil
+	}
+	bitrate := mp3Bitrates[table][bitrateIdx]
+	if bitrate == 0 {
+		return 0, fmt.Errorf("unsupported MPEG bitrate")
+	}
+	audioBytes := size - start - int64(idx)
+	return time.Duration(float64(audioBytes*8) / float64(bitrate*1000) * float64(time.Second)), nil
+}
+
+func wavDuration(file *os.File) (time.Duration, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(header[:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("WAVE")) {
+		return 0, fmt.Errorf("not a WAVE file")
+	}
+	var byteRate uint32
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(file, chunk); err != nil {
+			return 0, err
+		}
+		size := binary.LittleEndian.Uint32(chunk[4:])
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if size < 12 {
+				return 0, fmt.Errorf("malformed fmt chunk")
+			}
+			// Only the start of the chunk is needed, and its size cannot be trusted to be small.
+			format := make([]byte, min(size, 64))
+			if _, err := io.ReadFull(file, format); err != nil {
+				return 0, err
+			}
+			byteRate = binary.LittleEndian.Uint32(format[8:])
+			if _, err := file.Seek(int64(size-uint32(len(format))+size%2), io.SeekCurrent); err != nil {
+				return 0, err
+			}
+			continue
+		case "data":
+			if byteRate == 0 {
+				return 0, fmt.Errorf("data chunk precedes fmt chunk")
+			}
+			return seconds(uint64(size), int(byteRate)), nil
+		}
+		// Chunks are padded to an even number of bytes.
+		if _, err := file.Seek(int64(size+size%2), io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func flacDuration(file *os.File) (time.Duration, error) {
+	// The STREAMINFO block always comes first, immediately after the marker and its own 4 byte header.
+	header := make([]byte, 4+4+18)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(header[:4], []byte("fLaC")) || header[4]&0x7F != 0 {
+		return 0, fmt.Errorf("not a FLAC file")
+	}
+	info := binary.BigEndian.Uint64(header[8+10:])
+	sampleRate := int(info >> 44)
+	totalSamples := info & (1<<36 - 1)
+	if sampleRate == 0 || totalSamples == 0 {
+		return 0, fmt.Errorf("FLAC file does not give its length")
+	}
+	return seconds(totalSamples, sampleRate), nil
+}
+
+func oggDuration(file *os.File, size int64) (time.Duration, error) {
+	// The identification header of the stream is in the first page, which has a 27 byte header followed by the
+	// segment table.
+	first := make([]byte, 512)
+	n, err := file.ReadAt(first, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	first = first[:n]
+	if len(first) < 28 || !bytes.Equal(first[:4], []byte("OggS")) {
+		return 0, fmt.Errorf("not an Ogg file")
+	}
+	if 27+int(first[26]) > len(first) {
+		return 0, fmt.Errorf("malformed Ogg page")
+	}
+	packet := first[27+int(first[26]):]
+	var sampleRate int
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")) && len(packet) >= 16:
+		sampleRate = int(binary.LittleEndian.Uint32(packet[12:]))
+		if sampleRate == 0 {
+			return 0, fmt.Errorf("Vorbis stream does not give its sample rate")
+		}
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		// Opus granule positions always count samples at 48 kHz.
+		sampleRate = 48000
+	default:
+		return 0, fmt.Errorf("unsupported Ogg codec")
+	}
+	// The granule position of the last page is the total number of samples.
+	tail := make([]byte, min(size, 65536))
+	if _, err := file.ReadAt(tail, size-int64(len(tail))); err != nil && err != io.EOF {
+		return 0, err
+	}
+	last := bytes.LastIndex(tail, []byte("OggS"))
+	if last < 0 || last+14 > len(tail) {
+		return 0, fmt.Errorf("no final Ogg page found")
+	}
+	granule := binary.LittleEndian.Uint64(tail[last+6:])
+	return seconds(granule, sampleRate), nil
+}
@@ -0,0 +1,149 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	gmText "github.com/yuin/goldmark/text"
+	wwExt "wyweb.site/extensions"
+)
+
+// pageFeatures records what the document of a page contains, so that resources with conditions are placed only on
+// the pages needing them.
+type pageFeatures struct {
+	Code  bool
+	Math  bool
+	Alert bool
+	Media bool
+}
+
+// mathLanguages are the languages of code blocks holding math rather than code.
+var mathLanguages = []string{"math", "latex", "tex"}
+
+// scanFeatures walks a parsed document once, noting the features it contains.
+func scanFeatures(doc ast.Node, source []byte) pageFeatures {
+	var out pageFeatures
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindFencedCodeBlock:
+			lang := strings.ToLower(string(n.(*ast.FencedCodeBlock).Language(source)))
+			if slices.Contains(mathLanguages, lang) {
+				out.Math = true
+			} else {
+				out.Code = true
+			}
+			return ast.WalkSkipChildren, nil
+		case ast.KindCodeBlock:
+			out.Code = true
+			return ast.WalkSkipChildren, nil
+		case wwExt.KindMath, wwExt.KindMathBlock:
+			out.Math = true
+		case ast.KindRawHTML:
+			out.Math = out.Math || hasMathTag(n.(*ast.RawHTML).Segments, source)
+		case ast.KindHTMLBlock:
+			out.Math = out.Math || hasMathTag(n.Lines(), source)
+		case wwExt.KindAlert:
+			out.Alert = true
+		case wwExt.KindMedia:
+			if _, _, ok := wwExt.MediaInfo(n); ok {
+				out.Media = true
+			}
+			if embedded, embeddedSource, ok := wwExt.EmbeddedDocument(n); ok {
+				features := scanFeatures(embedded, embeddedSource)
+				out.Code = out.Code || features.Code
+				out.Math = out.Math || features.Math
+				out.Alert = out.Alert || features.Alert
+				out.Media = out.Media || features.Media
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return out
+}
+
+// hasMathTag reports whether raw HTML opens a MathML element.
+func hasMathTag(segments *gmText.Segments, source []byte) bool {
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		if bytes.Contains(segment.Value(source), []byte("<math")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a resource with the condition belongs on the page of node.
+func (cond *WWCondition) matches(node *ConfigNode) bool {
+	media := node.features.Media || node.NodeKind == WWPODCAST || node.Episode != nil
+	switch {
+	case cond.Code && !node.features.Code,
+		cond.Math && !node.features.Math,
+		cond.Alert && !node.features.Alert,
+		cond.Media && !media,
+		cond.Gallery && node.NodeKind != WWGALLERY:
+		return false
+	}
+	if len(cond.Kind) > 0 && !slices.Contains(cond.Kind, KindNames[node.NodeKind]) {
+		return false
+	}
+	if len(cond.Tag) > 0 && !slices.ContainsFunc(cond.Tag, func(tag string) bool {
+		return slices.ContainsFunc(node.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+	}) {
+		return false
+	}
+	return true
+}
+
+// automaticResources gives the resources whose conditions hold for the page, among those visible from it. A resource
+// shadowed by a nearer one of the same name, or excluded from the page, is left out.
+func (node *ConfigNode) automaticResources() []string {
+	out := make([]string, 0)
+	for key, res := range node.Tree.Resources {
+		if res.When == nil || node.lookupResource(resourceName(key)) != key || slices.Contains(node.excluded, key) {
+			continue
+		}
+		if res.When.matches(node) {
+			out = append(out, key)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
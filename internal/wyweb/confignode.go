@@ -39,7 +39,7 @@ import (
 	"html"
 	"log"
 	"math/bits"
-	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -70,20 +70,36 @@ type ConfigNode struct {
 	HTML           *HTMLElement
 	Children       map[string]*ConfigNode
 	LocalResources []string
+	resourceOrder  []string // LocalResources along with their dependencies, in the order they are placed on the page
+	resourceLock   sync.Mutex
+	excluded       []string     // Resources left out of the page, which are not placed on it even when their conditions hold
+	features       pageFeatures // What the document of the page contains, for resources that are placed conditionally
 	Data           *WyWebMeta
 	Parent         *ConfigNode
 	Index          string
 	TagDB          map[string][]Listable
 	Tags           []string
+	Series         WWSeries
+	SeriesPrev     WWNavLink
+	SeriesNext     WWNavLink
+	Podcast        WWPodcast
+	Episode        *PodcastEpisode
+	Recipe         *WWRecipe
+	Page           WWPage
 	Tree           *ConfigTree
 	ParsedDocument *ast.Node
+	parsedSource   []byte // The text ParsedDocument was parsed from, which its segments index into
 	Preview        string
 	RealPath       string
 	Images         []RichImage
 	StructuredData []string
 	Dependencies   map[string]DependencyKind //All files on which this node depends
 	knownFiles     []string
+	terms          map[string]float64
 	LastRead       time.Time
+	Lang           string                 // Language of a translated page; empty for the page as written
+	variantOf      *ConfigNode            // The page a translation was made from
+	variants       map[string]*ConfigNode // Translations of the page by language, made as they are requested
 }
 
 type Listable interface {
@@ -92,6 +108,8 @@ type Listable interface {
 	GetTitle() string
 	SetID()
 	AsRSSItem() *HTMLElement
+	AsAtomEntry() *HTMLElement
+	AsJSONFeedItem() map[string]interface{}
 }
 
 func (n *ConfigNode) GetDate() time.Time {
@@ -159,12 +177,104 @@ func (n *ConfigNode) SetID() {
 	//fmt.Printf("%064b\n\n", n.id)
 }
 
+// isArticle reports whether the node is a page of writing, i.e. a post or a recipe. Articles are what listings,
+// feeds, tag pages and the archive are made of.
+func (n *ConfigNode) isArticle() bool {
+	return n.NodeKind == WWPOST || n.NodeKind == WWRECIPE
+}
+
 func (n *ConfigNode) AsRSSItem() *HTMLElement {
 	item := NewHTMLElement("item")
 	item.AppendNew("title").AppendText(n.Title)
-	item.AppendNew("link").AppendText("https://" + n.Tree.Domain + "/" + n.Path)
+	item.AppendNew("link").Compact().AppendText(n.pageURL())
 	item.AppendNew("description").AppendText(html.EscapeString(n.Description))
 	item.AppendNew("pubDate").AppendText(n.Date.Format(time.RFC1123Z))
+	for _, tag := range n.Tags {
+		item.AppendNew("category").AppendText(html.EscapeString(tag))
+	}
+	if !n.isArticle() {
+		return item
+	}
+	for _, enc := range n.mediaEnclosures() {
+		item.AppendNew("enclosure", map[string]string{
+			"url":    enc.URL,
+			"length": strconv.FormatInt(enc.Length, 10),
+			"type":   enc.Type,
+		}).SetSelfClosing(true)
+	}
+	if n.Feed.FullContent {
+		item.AppendNew("content:encoded").AppendText(html.EscapeString(n.feedContent())).NoIndent()
+	}
+	return item
+}
+
+func (n *ConfigNode) AsAtomEntry() *HTMLElement {
+	link := n.pageURL()
+	entry := NewHTMLElement("entry")
+	entry.AppendNew("title").AppendText(html.EscapeString(n.Title))
+	entry.AppendNew("id").Compact().AppendText(tagURI(n.Tree.Domain, n.Date, n.Path+n.langQuery()))
+	entry.AppendNew("link", Href(link), map[string]string{"rel": "alternate", "type": "text/html"}).SetSelfClosing(true)
+	entry.AppendNew("published").AppendText(n.Date.Format(time.RFC3339))
+	entry.AppendNew("updated").AppendText(n.Updated.Format(time.RFC3339))
+	if n.Author != "" {
+		entry.AppendNew("author").AppendNew("name").AppendText(html.EscapeString(n.Author))
+	}
+	entry.AppendNew("summary", map[string]string{"type": "html"}).AppendText(html.EscapeString(n.Description))
+	for _, tag := range n.Tags {
+		entry.AppendNew("category", map[string]string{"term": tag}).SetSelfClosing(true)
+	}
+	if !n.isArticle() {
+		return entry
+	}
+	for _, enc := range n.mediaEnclosures() {
+		entry.AppendNew("link", Href(enc.URL), map[string]string{
+			"rel":    "enclosure",
+			"type":   enc.Type,
+			"length": strconv.FormatInt(enc.Length, 10),
+		}).SetSelfClosing(true)
+	}
+	if n.Feed.FullContent {
+		entry.AppendNew("content", map[string]string{"type": "html"}).AppendText(html.EscapeString(n.feedContent())).NoIndent()
+	}
+	return entry
+}
+
+func (n *ConfigNode) AsJSONFeedItem() map[string]interface{} {
+	link := n.pageURL()
+	item := map[string]interface{}{
+		"id":             tagURI(n.Tree.Domain, n.Date, n.Path+n.langQuery()),
+		"url":            link,
+		"title":          n.Title,
+		"content_html":   n.Preview,
+		"date_published": n.Date.Format(time.RFC3339),
+		"date_modified":  n.Updated.Format(time.RFC3339),
+	}
+	if n.Description != "" {
+		item["summary"] = n.Description
+	}
+	if n.Author != "" {
+		item["authors"] = []map[string]string{{"name": n.Author}}
+	}
+	if len(n.Tags) > 0 {
+		item["tags"] = n.Tags
+	}
+	if !n.isArticle() {
+		return item
+	}
+	if n.Feed.FullContent {
+		item["content_html"] = n.feedContent()
+	}
+	attachments := make([]map[string]interface{}, 0)
+	for _, enc := range n.mediaEnclosures() {
+		attachments = append(attachments, map[string]interface{}{
+			"url":           enc.URL,
+			"mime_type":     enc.Type,
+			"size_in_bytes": enc.Length,
+		})
+	}
+	if len(attachments) > 0 {
+		item["attachments"] = attachments
+	}
 	return item
 }
 
@@ -193,18 +303,30 @@ type RawResource struct {
 }
 
 type HTMLHeadData struct {
-	Title   string
-	Meta    []string
-	Styles  []interface{}
-	Scripts []interface{}
+	Title       string
+	Lang        string
+	Meta        []string
+	Hints       []ResourceHint
+	Styles      []interface{}
+	Scripts     []interface{}
+	BodyStyles  []interface{} // Placed at the end of the body rather than in the head
+	BodyScripts []interface{}
+	Robots      map[string]string
+	Social      []SocialMeta
+	Alternates  []LangAlternate
 }
 
 func (node *ConfigNode) GetHTMLHeadData() *HTMLHeadData {
 	//node.RLock()
 	//defer node.RUnlock()
+	node.Tree.RLock()
+	defer node.Tree.RUnlock()
+	hints := make([]ResourceHint, 0)
 	styles := make([]interface{}, 0)
 	scripts := make([]interface{}, 0)
-	for _, name := range node.LocalResources {
+	bodyStyles := make([]interface{}, 0)
+	bodyScripts := make([]interface{}, 0)
+	for _, name := range node.orderedResources() {
 		res, ok := node.Tree.Resources[name]
 		if !ok {
 			log.Printf("%s does not exist in the resource registry.\n", name)
@@ -215,32 +337,49 @@ func (node *ConfigNode) GetHTMLHeadData() *HTMLHeadData {
 		}
 		var value interface{}
 		switch res.Method {
-		case "raw":
-			value = RawResource{String: res.Value, Attributes: res.Attributes}
+		case "raw", "local":
+			if node.Tree.mustFingerprint(name, res) {
+				res.Fingerprint = true
+			}
+			value = node.Tree.processedResource(name, res)
 		case "url":
 			value = URLResource{String: res.Value, Attributes: res.Attributes}
-		case "local":
-			temp, err := os.ReadFile(res.Value)
-			if err == nil {
-				value = RawResource{String: string(temp), Attributes: res.Attributes}
-			}
 		default:
 			log.Printf("Unknown method for resource %s: %s\n", name, res.Type)
 		}
-		switch res.Type {
-		case "style":
+		if value == nil {
+			continue
+		}
+		value = node.Tree.loadingAttributes(name, res, value)
+		if hint, ok := resourceHint(res, value); ok {
+			hints = append(hints, hint)
+		}
+		inBody := node.Tree.inBody(name)
+		switch {
+		case res.Type == "style" && inBody:
+			bodyStyles = append(bodyStyles, value)
+		case res.Type == "style":
 			styles = append(styles, value)
-		case "script":
+		case res.Type == "script" && inBody:
+			bodyScripts = append(bodyScripts, value)
+		case res.Type == "script":
 			scripts = append(scripts, value)
 		default:
 			log.Printf("Unknown type for resource %s: %s\n", name, res.Type)
 		}
 	}
 	out := &HTMLHeadData{
-		Title:   node.Title,
-		Meta:    node.Meta,
-		Styles:  styles,
-		Scripts: scripts,
+		Title:       node.Title,
+		Lang:        node.htmlLang(),
+		Meta:        node.Meta,
+		Hints:       hints,
+		Styles:      styles,
+		Scripts:     scripts,
+		BodyStyles:  bodyStyles,
+		BodyScripts: bodyScripts,
+		Robots:      node.Robots.Directives(),
+		Social:      node.SocialMeta(),
+		Alternates:  node.Alternates(),
 	}
 	return out
 }
@@ -43,6 +43,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"wyweb.site/util"
@@ -52,9 +53,24 @@ import (
 type ConfigTree struct {
 	Root         *ConfigNode
 	TagDB        map[string][]Listable
+	SeriesDB     map[string][]*ConfigNode
 	Resources    map[string]Resource
 	DocumentRoot string
+	rootDir      string // DocumentRoot as an absolute path, since the working directory changes with each request
 	Domain       string
+	Publisher    WWPublisher
+	Theme        *Theme
+	Languages    []string // The first is the language pages are written in unless translated
+	locales      map[string]*Locale
+	Menu         []WWMenuEntry
+	menuMissing  map[string]bool // Pages named by the menu that could not be found when last checked
+	related      map[string][]*ConfigNode
+	relatedLock  sync.Mutex
+	sitemaps     map[string][]byte
+	assets       map[string]*asset
+	pinned       map[string]string // Integrity hashes of pinned url resources, by URL
+	assetLock    sync.Mutex
+	changed      atomic.Bool // Set whenever a node is added, updated, or removed
 	sync.RWMutex
 }
 
@@ -71,10 +87,9 @@ func (tree *ConfigTree) SetResource(name string, res Resource) {
 	tree.Resources[name] = res
 }
 
-// Create a new configNode from cfg and add it to the tree
+// Create a new configNode from cfg and add it to the tree. The tree is not locked, since resolving the new node locks
+// it to register the node's resources.
 func (tree *ConfigTree) RegisterConfig(cfg *WyWebMeta) (*ConfigNode, error) {
-	tree.Lock()
-	defer tree.Unlock()
 	rootPath := util.PathToList(tree.Root.Path)
 	thisPath := util.PathToList((*cfg).GetPath())
 	_, err := util.NearestCommonAncestor(rootPath, thisPath)
@@ -130,6 +145,14 @@ func createNodeFromPath(parent *ConfigNode, dir string, file os.DirEntry) (*Conf
 	if !file.IsDir() {
 		if strings.HasSuffix(filename, ".post.md") {
 			child = MagicPost(parent, filename)
+		} else if strings.HasSuffix(filename, ".page.md") {
+			child = MagicPage(parent, filename)
+		} else if parent.NodeKind == WWPODCAST && isEpisodeAudio(filename) {
+			var err error
+			child, err = MagicEpisode(parent, filename)
+			if err != nil {
+				return nil, err
+			}
 		} else {
 			return nil, fmt.Errorf("%s could not be interpreted as a WyWeb page", filename)
 		}
@@ -175,7 +198,7 @@ func (node *ConfigNode) growTree(dir string, tree *ConfigTree) error {
 	newNodeCreated := false
 	for _, file := range files {
 		path := filepath.Join(dir, file.Name())
-		key := util.TrimMagicSuffix(path)
+		key := node.childKey(path)
 		info, err := file.Info()
 		if err != nil {
 			continue
@@ -209,8 +232,11 @@ func (node *ConfigNode) growTree(dir string, tree *ConfigTree) error {
 	}
 	if newNodeCreated {
 		setNavLinksOfChildren(node)
-		if node.NodeKind == WWLISTING {
+		tree.invalidateRelated()
+		tree.changed.Store(true)
+		if node.NodeKind == WWLISTING || node.NodeKind == WWPODCAST {
 			node.HTML = nil
+			node.forgetVariants()
 		}
 	} else {
 		status = fmt.Errorf("no new files found")
@@ -231,11 +257,19 @@ func BuildConfigTree(documentRoot string, domain string) (*ConfigTree, error) {
 	out := ConfigTree{
 		Domain:       domain,
 		DocumentRoot: documentRoot,
+		rootDir:      documentRoot,
 		Root:         &rootnode,
 		Resources:    make(map[string]Resource),
 		TagDB:        make(map[string][]Listable),
+		SeriesDB:     make(map[string][]*ConfigNode),
+		related:      make(map[string][]*ConfigNode),
+		assets:       make(map[string]*asset),
+		pinned:       make(map[string]string),
 	}
 	rootnode.Tree = &out
+	if abs, err := filepath.Abs(documentRoot); err == nil {
+		out.rootDir = abs
+	}
 	meta, err := ReadWyWeb(documentRoot)
 	if err != nil {
 		log.Printf("Document root: %s\n", documentRoot)
@@ -244,19 +278,41 @@ func BuildConfigTree(documentRoot string, domain string) (*ConfigTree, error) {
 	if (meta).GetType() != WWROOT {
 		return nil, fmt.Errorf("the wyweb file located at %s must be of type root", documentRoot)
 	}
+	out.Publisher = (meta).(*WyWebRoot).Publisher
+	for _, lang := range (meta).(*WyWebRoot).Languages {
+		out.Languages = append(out.Languages, strings.ToLower(lang))
+	}
+	out.locales = loadLocales((meta).(*WyWebRoot).Locales)
+	out.Menu = (meta).(*WyWebRoot).Menu
+	if theme := (meta).(*WyWebRoot).Theme; theme != "" {
+		out.Theme, err = LoadTheme(filepath.Join(documentRoot, theme))
+		if err != nil {
+			log.Printf("WARN: could not load theme %s: %s", theme, err.Error())
+		}
+	}
 	for k, v := range (meta).(*WyWebRoot).Resources {
+		v.DependsOn = rootnode.lookupResources(v.DependsOn)
 		out.Resources[k] = v
+		if v.Method == "local" {
+			rootnode.Dependencies[v.Value] = KindResourceLocal
+		}
 	}
+	registerDefaultTheme(out.Resources)
 	rootnode.Data = &meta
 	rootnode.growTree(documentRoot, &out)
+	out.registerHighlightStyles(rootnode.Highlight)
 	//for tag, lst := range out.TagDB {
 	//	fmt.Printf("\n%s:\n\t", tag)
 	//	for _, item := range lst {
 	//		fmt.Printf("%s, ", item.GetTitle())
 	//	}
 	//}
+	out.checkMenu()
+	out.checkResourceDeps()
+	out.processResources()
 	out.MakeSitemap()
-	out.MakeRSS()
+	out.MakeFeeds()
+	out.changed.Store(false)
 	go out.watchForDependencyChanges(time.Second)
 	return &out, nil
 }
@@ -296,10 +352,10 @@ func (node *ConfigNode) GetItemsByTag(tag string) []Listable {
 }
 
 func (tree *ConfigTree) GetDefaultHead() *HTMLHeadData {
-	tree.RLock()
-	defer tree.RUnlock()
 	out := tree.Root.GetHTMLHeadData()
 	(*out).Title = ""
+	(*out).Social = nil
+	(*out).Alternates = nil
 	return out
 }
 
@@ -323,6 +379,7 @@ func watchRecurse(node *ConfigNode) {
 			st, err := os.Stat(path)
 			if errors.Is(err, os.ErrNotExist) && (kind == KindWyWeb || kind == KindMDSource) {
 				log.Println("REMOVING ", child.Title)
+				child.unregisterSeries()
 				staleIDs[key] = child.GetIDb64()
 				needsRemoval = append(needsRemoval, key)
 				break
@@ -333,7 +390,9 @@ func watchRecurse(node *ConfigNode) {
 			}
 			if st.ModTime().After(child.LastRead.Add(time.Second)) {
 				modifiedDep = true
-				break
+				if kind == KindResourceLocal {
+					node.Tree.expireAsset(path)
+				}
 			}
 		}
 		if modifiedDep {
@@ -358,6 +417,7 @@ func watchRecurse(node *ConfigNode) {
 			log.Println(err.Error())
 			continue
 		}
+		node.Children[staleNode].unregisterSeries()
 		delete((*node).Children, staleNode)
 		err = newChild.resolve()
 		if err != nil {
@@ -374,11 +434,19 @@ func watchRecurse(node *ConfigNode) {
 	}
 	if len(needsUpdate) > 0 || len(needsRemoval) > 0 {
 		setNavLinksOfChildren(node)
+		node.Tree.invalidateRelated()
+		node.Tree.changed.Store(true)
 	}
 	for _, child := range node.Children {
 		watchRecurse(child)
 	}
-	if node.NodeKind == WWLISTING {
+	if node.NodeKind == WWLISTING || node.NodeKind == WWPODCAST {
+		// Markup rendered from a theme cannot be patched in place, so such a listing is built again instead.
+		if node.Tree.Theme != nil && (len(needsUpdate) > 0 || len(needsRemoval) > 0) {
+			node.invalidate()
+		} else if len(needsUpdate) > 0 || len(needsRemoval) > 0 {
+			node.forgetVariants()
+		}
 		for _, staleNode := range needsUpdate {
 			oldlisting, err := node.HTML.GetElementByID(staleIDs[staleNode])
 			if err != nil {
@@ -398,8 +466,20 @@ func watchRecurse(node *ConfigNode) {
 
 func (tree *ConfigTree) watchForDependencyChanges(frequency time.Duration) {
 	for {
+		tree.reloadTheme()
+		// The root is never the child of another node, so the files of its resources are checked here.
+		for path, kind := range tree.Root.Dependencies {
+			if kind == KindResourceLocal {
+				tree.expireAsset(path)
+			}
+		}
 		watchRecurse(tree.Root)
 		tree.Root.growTree(filepath.Base(tree.DocumentRoot), tree)
+		if tree.changed.Swap(false) {
+			tree.checkResourceDeps()
+			tree.checkMenu()
+			tree.MakeSitemap()
+		}
 		time.Sleep(frequency)
 	}
 }
@@ -0,0 +1,89 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	_ "embed"
+	"slices"
+)
+
+// Names under which the built-in theme is registered in ConfigTree.Resources.
+// A root wyweb file may define resources with the same names to replace them.
+const (
+	DefaultStyleName    = "wyweb-style"
+	DefaultLightboxName = "wyweb-lightbox"
+)
+
+//go:embed defaulttheme/wyweb.css
+var defaultStyleString string
+
+//go:embed defaulttheme/lightbox.js
+var defaultLightboxString string
+
+// DefaultResources lists the built-in theme resources included on every page.
+// The lightbox is placed on galleries only, by its condition.
+var DefaultResources = []string{DefaultStyleName}
+
+// registerDefaultTheme adds the built-in theme to resources without
+// overwriting anything the site already defines under the same names.
+func registerDefaultTheme(resources map[string]Resource) {
+	if _, ok := resources[DefaultStyleName]; !ok {
+		resources[DefaultStyleName] = Resource{
+			Type:   "style",
+			Method: "raw",
+			Value:  defaultStyleString,
+		}
+	}
+	if _, ok := resources[DefaultLightboxName]; !ok {
+		resources[DefaultLightboxName] = Resource{
+			Type:      "script",
+			Method:    "raw",
+			Value:     defaultLightboxString,
+			DependsOn: []string{DefaultStyleName},
+			When:      &WWCondition{Gallery: true},
+		}
+	}
+}
+
+// defaultRootResources prepends the built-in theme to the resources the root
+// includes by default, leaving out any the root excludes.
+func defaultRootResources(defaults []string, exclude []string) []string {
+	out := make([]string, 0, len(DefaultResources)+len(defaults))
+	for _, name := range DefaultResources {
+		if !slices.Contains(exclude, name) && !slices.Contains(defaults, name) {
+			out = append(out, name)
+		}
+	}
+	return append(out, defaults...)
+}
@@ -0,0 +1,148 @@
+/*
+ * WyWeb default theme: gallery lightbox.
+ *
+ * Opens gallery images (img.gallery-image with a data-fullsize attribute) in
+ * an overlay. Captions are fetched from the gallery's ?info= endpoint, and
+ * the open image is mirrored into the ?image= query parameter so the URL can
+ * be shared.
+ */
+(function () {
+	"use strict";
+
+	var cache = {};
+
+	function field(parent, cls, text) {
+		if (!text) {
+			return;
+		}
+		var el = document.createElement("div");
+		el.className = cls;
+		el.textContent = text;
+		parent.appendChild(el);
+	}
+
+	function fetchInfo(id) {
+		if (!id) {
+			return Promise.resolve(null);
+		}
+		if (cache[id]) {
+			return Promise.resolve(cache[id]);
+		}
+		return fetch(location.pathname + "?info=" + encodeURIComponent(id))
+			.then(function (resp) { return resp.ok ? resp.json() : {}; })
+			.then(function (data) {
+				cache[id] = data[id] || null;
+				return cache[id];
+			})
+			.catch(function () { return null; });
+	}
+
+	function setImageParam(id) {
+		if (!window.history || !history.replaceState) {
+			return;
+		}
+		var url = new URL(location.href);
+		if (id) {
+			url.searchParams.set("image", id);
+		} else {
+			url.searchParams.delete("image");
+		}
+		history.replaceState(null, "", url);
+	}
+
+	function init() {
+		var images = Array.prototype.slice.call(
+			document.querySelectorAll("img.gallery-image[data-fullsize]"));
+		if (images.length === 0) {
+			return;
+		}
+		images.sort(function (a, b) {
+			return Number(a.dataset.imageNum) - Number(b.dataset.imageNum);
+		});
+
+		var box = document.createElement("div");
+		box.className = "wyweb-lightbox";
+		box.hidden = true;
+		box.setAttribute("role", "dialog");
+		box.setAttribute("aria-modal", "true");
+		box.innerHTML =
+			'<button type="button" class="wyweb-lightbox-close" aria-label="Close">&times;</button>' +
+			'<button type="button" class="wyweb-lightbox-prev" aria-label="Previous">&lsaquo;</button>' +
+			'<button type="button" class="wyweb-lightbox-next" aria-label="Next">&rsaquo;</button>' +
+			'<figure><img alt=""><figcaption></figcaption></figure>';
+		document.body.appendChild(box);
+
+		var full = box.querySelector("img");
+		var caption = box.querySelector("figcaption");
+		var current = -1;
+
+		function show(idx) {
+			current = (idx + images.length) % images.length;
+			var thumb = images[current];
+			full.src = thumb.dataset.fullsize;
+			full.alt = thumb.alt || "";
+			caption.textContent = "";
+			box.hidden = false;
+			setImageParam(thumb.id);
+			fetchInfo(thumb.id).then(function (info) {
+				if (!info || images[current] !== thumb) {
+					return;
+				}
+				field(caption, "wyweb-lightbox-title", info.title);
+				field(caption, "wyweb-lightbox-meta",
+					[info.artist, info.medium, info.location].filter(Boolean).join(" · "));
+				field(caption, "wyweb-lightbox-description", info.description);
+			});
+		}
+
+		function hide() {
+			box.hidden = true;
+			full.removeAttribute("src");
+			current = -1;
+			setImageParam("");
+		}
+
+		images.forEach(function (img, idx) {
+			img.addEventListener("click", function (e) {
+				e.preventDefault();
+				show(idx);
+			});
+		});
+		box.querySelector(".wyweb-lightbox-close").addEventListener("click", hide);
+		box.querySelector(".wyweb-lightbox-prev").addEventListener("click", function () { show(current - 1); });
+		box.querySelector(".wyweb-lightbox-next").addEventListener("click", function () { show(current + 1); });
+		box.addEventListener("click", function (e) {
+			if (e.target === box) {
+				hide();
+			}
+		});
+		document.addEventListener("keydown", function (e) {
+			if (box.hidden) {
+				return;
+			}
+			if (e.key === "Escape") {
+				hide();
+			} else if (e.key === "ArrowLeft") {
+				show(current - 1);
+			} else if (e.key === "ArrowRight") {
+				show(current + 1);
+			}
+		});
+
+		var wanted = new URLSearchParams(location.search).get("image");
+		if (wanted) {
+			for (var i = 0; i < images.length; i++) {
+				if (images[i].id === wanted) {
+					show(i);
+					break;
+				}
+			}
+		}
+	}
+
+	if (document.readyState === "loading") {
+		document.addEventListener("DOMContentLoaded", init);
+	} else {
+		init();
+	}
+})();
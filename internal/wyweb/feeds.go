@@ -0,0 +1,449 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+
+	wwExt "wyweb.site/extensions"
+)
+
+// Everything needed to describe a page in a feed, independent of the feed format.
+type feedInfo struct {
+	Title       string
+	Description string
+	Author      string
+	Copyright   string
+	BaseURL     string
+	Link        string    // URL of the page the feed describes
+	Dir         string    // Directory in which the feed files are written
+	URLPath     string    // Path from which the feeds are served, if it differs from Dir
+	Updated     time.Time // Most recent explicit date of the page or any of its children
+	Lang        string    // Language of the items, if the site names its languages
+	Translation bool      // Whether the feed is of a translation, whose files are named e.g. rssfeed.es.xml
+}
+
+// filename gives the name of the file of a feed format, which for a translation includes the language.
+func (f *feedInfo) filename(name string) string {
+	if !f.Translation {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + f.Lang + ext
+}
+
+func (f *feedInfo) feedURL(filename string) string {
+	filename = f.filename(filename)
+	if f.URLPath != "" {
+		return f.BaseURL + f.URLPath + "/" + filename
+	}
+	return f.BaseURL + filepath.Join(f.Dir, filename)
+}
+
+// tagURI builds an RFC 4151 tag URI. Unlike the numeric ids of nodes, these do not change when an item is updated,
+// so they are suitable as permanent ids for feed entries.
+func tagURI(domain string, date time.Time, path string) string {
+	return fmt.Sprintf("tag:%s,%s:/%s", domain, date.Format(time.DateOnly), path)
+}
+
+func writeFeedFile(path string, data []byte) {
+	feedFile, err := os.Create(path)
+	if err != nil && !os.IsExist(err) {
+		fmt.Printf("%+v\n", err)
+		return
+	}
+	defer feedFile.Close()
+	feedFile.Write(data)
+}
+
+func (f *feedInfo) renderRSS(items []Listable) []byte {
+	var rssXML bytes.Buffer
+	rssXML.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>`)
+	rssXML.WriteByte('\n')
+	feed := NewHTMLElement("rss", map[string]string{
+		"version":       "2.0",
+		"xmlns:atom":    "http://www.w3.org/2005/Atom",
+		"xmlns:content": "http://purl.org/rss/1.0/modules/content/",
+	})
+	channel := feed.AppendNew("channel")
+	channel.AppendNew("title").AppendText(f.Title)
+	channel.AppendNew("description").AppendText(f.Description)
+	channel.AppendNew("link").Compact().AppendText(f.Link)
+	if f.Lang != "" {
+		channel.AppendNew("language").AppendText(f.Lang)
+	}
+	channel.AppendNew("copyright").AppendText(f.Copyright)
+	channel.AppendNew("lastBuildDate").AppendText(time.Now().Format(time.RFC1123Z))
+	channel.AppendNew("pubDate").AppendText(f.Updated.Format(time.RFC1123Z))
+	channel.AppendNew("ttl").AppendText("60")
+	channel.AppendNew("atom:link", Href(f.feedURL("rssfeed.xml")), map[string]string{"rel": "self", "type": "application/rss+xml"}).SetSelfClosing(true)
+	for _, item := range items {
+		channel.Append(item.AsRSSItem())
+	}
+	RenderHTML(feed, &rssXML)
+	return rssXML.Bytes()
+}
+
+func (f *feedInfo) renderAtom(items []Listable) []byte {
+	var atomXML bytes.Buffer
+	atomXML.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>`)
+	atomXML.WriteByte('\n')
+	feed := NewHTMLElement("feed", map[string]string{"xmlns": "http://www.w3.org/2005/Atom"})
+	if f.Lang != "" {
+		feed.Attributes["xml:lang"] = f.Lang
+	}
+	feed.AppendNew("title").AppendText(html.EscapeString(f.Title))
+	if f.Description != "" {
+		feed.AppendNew("subtitle").AppendText(html.EscapeString(f.Description))
+	}
+	feed.AppendNew("id").Compact().AppendText(f.Link)
+	feed.AppendNew("link", Href(f.Link), map[string]string{"rel": "alternate", "type": "text/html"}).SetSelfClosing(true)
+	feed.AppendNew("link", Href(f.feedURL("atom.xml")), map[string]string{"rel": "self", "type": "application/atom+xml"}).SetSelfClosing(true)
+	feed.AppendNew("updated").AppendText(f.Updated.Format(time.RFC3339))
+	if f.Author != "" {
+		feed.AppendNew("author").AppendNew("name").AppendText(html.EscapeString(f.Author))
+	}
+	if f.Copyright != "" {
+		feed.AppendNew("rights").AppendText(html.EscapeString(f.Copyright))
+	}
+	feed.AppendNew("generator", map[string]string{"uri": "https://wyweb.site"}).AppendText("WyWeb")
+	for _, item := range items {
+		feed.Append(item.AsAtomEntry())
+	}
+	RenderHTML(feed, &atomXML)
+	return atomXML.Bytes()
+}
+
+func (f *feedInfo) renderJSONFeed(items []Listable) []byte {
+	feedItems := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		feedItems[i] = item.AsJSONFeedItem()
+	}
+	feed := map[string]interface{}{
+		"version":       "https://jsonfeed.org/version/1.1",
+		"title":         f.Title,
+		"home_page_url": f.Link,
+		"feed_url":      f.feedURL("feed.json"),
+		"items":         feedItems,
+	}
+	if f.Description != "" {
+		feed["description"] = f.Description
+	}
+	if f.Lang != "" {
+		feed["language"] = f.Lang
+	}
+	if f.Author != "" {
+		feed["authors"] = []map[string]string{{"name": f.Author}}
+	}
+	data, err := json.MarshalIndent(feed, "", "    ")
+	if err != nil {
+		log.Println(err.Error())
+		return nil
+	}
+	return data
+}
+
+// Feed formats by the name of the file in which they are served, along with their content types.
+var feedFormats = map[string]struct {
+	contentType string
+	render      func(*feedInfo, []Listable) []byte
+}{
+	"rssfeed.xml": {"application/rss+xml; charset=utf-8", (*feedInfo).renderRSS},
+	"atom.xml":    {"application/atom+xml; charset=utf-8", (*feedInfo).renderAtom},
+	"feed.json":   {"application/feed+json; charset=utf-8", (*feedInfo).renderJSONFeed},
+}
+
+// writeAll writes the RSS, Atom, and JSON feeds for the same set of items.
+func (f *feedInfo) writeAll(items []Listable) {
+	for filename, format := range feedFormats {
+		writeFeedFile(filepath.Join(f.Dir, f.filename(filename)), format.render(f, items))
+	}
+}
+
+func sortByDateDescending(items []Listable) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].GetDate().After(items[j].GetDate())
+	})
+}
+
+// MakeFeeds writes the feeds of a listing, gallery, or podcast into its directory and returns the items they contain.
+// A podcast additionally gets a podcast feed in podcast.xml.
+func (node *ConfigNode) MakeFeeds() []Listable {
+	if !(node.NodeKind == WWGALLERY || node.NodeKind == WWLISTING || node.NodeKind == WWPODCAST) {
+		return nil
+	}
+	baseURL := "https://" + node.Tree.Domain + "/"
+	updated, _ := node.getMostRecentDates()
+	info := feedInfo{
+		Title:       node.Title,
+		Description: node.Description,
+		Author:      node.Author,
+		Copyright:   node.Copyright,
+		BaseURL:     baseURL,
+		Link:        baseURL + node.Path,
+		Dir:         node.RealPath,
+		Updated:     updated,
+		Lang:        node.Tree.DefaultLanguage(),
+	}
+	children := make([]Listable, 0)
+	switch node.NodeKind {
+	case WWLISTING, WWPODCAST:
+		for _, child := range node.Children {
+			if child.Draft || child.NodeKind == WWPAGE {
+				continue
+			}
+			children = append(children, child)
+		}
+	case WWGALLERY:
+		for idx := range node.Images {
+			children = append(children, &node.Images[idx])
+		}
+	}
+	sortByDateDescending(children)
+	info.writeAll(children)
+	if node.NodeKind == WWPODCAST {
+		writeFeedFile(filepath.Join(info.Dir, "podcast.xml"), node.renderPodcast(&info, children))
+	}
+	return children
+}
+
+// MakeFeeds writes the sitewide feeds, which contain the items of every listing and gallery, into the working
+// directory.
+func (tree *ConfigTree) MakeFeeds() {
+	baseURL := "https://" + tree.Domain + "/"
+	title := tree.Domain
+	if tree.Root.Title != "" {
+		title = tree.Root.Title
+	}
+	updated, _ := tree.Root.getMostRecentDates()
+	info := feedInfo{
+		Title:       title,
+		Description: tree.Root.Description,
+		Author:      tree.Root.Author,
+		Copyright:   tree.Root.Copyright,
+		BaseURL:     baseURL,
+		Link:        baseURL,
+		Updated:     updated,
+		Lang:        tree.DefaultLanguage(),
+	}
+	items := make([]Listable, 0)
+	var dft func(*ConfigNode)
+	dft = func(node *ConfigNode) {
+		temp := node.MakeFeeds()
+		if temp != nil {
+			items = append(items, temp...)
+		}
+		for _, child := range node.Children {
+			dft(child)
+		}
+	}
+	dft(tree.Root)
+	sortByDateDescending(items)
+	info.writeAll(items)
+	for _, lang := range tree.Languages[min(1, len(tree.Languages)):] {
+		tree.makeTranslatedFeeds(info, lang)
+	}
+}
+
+// makeTranslatedFeeds writes the feeds of every listing that has posts translated into lang, along with a sitewide
+// feed of them all. Only the translated posts are included.
+func (tree *ConfigTree) makeTranslatedFeeds(info feedInfo, lang string) {
+	root := tree.Root.Variant(lang)
+	info.Title = root.Title
+	info.Description = root.Description
+	info.Link = root.pageURL()
+	info.Lang = lang
+	info.Translation = true
+	items := make([]Listable, 0)
+	var dft func(*ConfigNode)
+	dft = func(node *ConfigNode) {
+		if node.NodeKind == WWLISTING || node.NodeKind == WWPODCAST {
+			children := make([]Listable, 0)
+			for _, child := range node.Children {
+				if child.Draft || child.NodeKind == WWPAGE || !child.HasLanguage(lang) {
+					continue
+				}
+				children = append(children, child.Variant(lang))
+			}
+			if len(children) > 0 {
+				sortByDateDescending(children)
+				variant := node.Variant(lang)
+				local := info
+				local.Title = variant.Title
+				local.Description = variant.Description
+				local.Link = variant.pageURL()
+				local.Dir = node.RealPath
+				local.Updated = children[0].GetDate()
+				local.writeAll(children)
+				items = append(items, children...)
+			}
+		}
+		for _, child := range node.Children {
+			dft(child)
+		}
+	}
+	dft(tree.Root)
+	sortByDateDescending(items)
+	info.writeAll(items)
+}
+
+// TagFeed renders a feed of every item tagged with tag. Filename selects the format, as in the feeds of listings.
+func (tree *ConfigTree) TagFeed(tag string, filename string) ([]byte, string, error) {
+	format, ok := feedFormats[filename]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown feed format %s", filename)
+	}
+	items := tree.GetItemsByTag(tag)
+	if len(items) == 0 {
+		return nil, "", fmt.Errorf("no items tagged with %s", tag)
+	}
+	sorted := make([]Listable, len(items))
+	copy(sorted, items)
+	sortByDateDescending(sorted)
+	baseURL := "https://" + tree.Domain + "/"
+	title := tree.Domain
+	if tree.Root.Title != "" {
+		title = tree.Root.Title
+	}
+	var updated time.Time
+	for _, item := range sorted {
+		if item.GetDate().After(updated) {
+			updated = item.GetDate()
+		}
+	}
+	info := feedInfo{
+		Title:       fmt.Sprintf("%s: %s", title, tag),
+		Description: fmt.Sprintf("Items tagged with %s", tag),
+		Author:      tree.Root.Author,
+		Copyright:   tree.Root.Copyright,
+		BaseURL:     baseURL,
+		Link:        baseURL + "tags?" + url.Values{"tags": {tag}}.Encode(),
+		URLPath:     "tags/" + url.PathEscape(tag),
+		Updated:     updated,
+	}
+	return format.render(&info, sorted), format.contentType, nil
+}
+
+type enclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// Matches root-relative URLs, but not protocol-relative ones.
+var rootRelativeURL = regexp.MustCompile(`(src|href)="/([^/])`)
+
+func absoluteURLs(text string, baseURL string) string {
+	return rootRelativeURL.ReplaceAllString(text, `$1="`+baseURL+`$2`)
+}
+
+// feedContent renders the full text of a post for inclusion in a feed. The title is left out, since every format
+// carries it separately, and links are made absolute so that they work outside of the site.
+func (node *ConfigNode) feedContent() string {
+	var card bytes.Buffer
+	if node.Recipe != nil {
+		RenderHTML(BuildRecipeCard(node, 0, ""), &card)
+		if node.Index == "" {
+			return absoluteURLs(card.String(), "https://"+node.Tree.Domain+"/")
+		}
+	}
+	text, err := os.ReadFile(node.Index)
+	if err != nil {
+		log.Println(err.Error())
+		return ""
+	}
+	sourceEmbeds := make([]string, 0)
+	md := newMarkdown(node.Path, &sourceEmbeds)
+	doc := ParsePost(md, text, node.Index)
+	if titleNode := findTitleHeading(doc); titleNode != nil {
+		doc.RemoveChild(doc, titleNode)
+	}
+	var buf bytes.Buffer
+	err = md.Renderer().Render(&buf, text, doc)
+	if err != nil {
+		log.Println(err.Error())
+		return ""
+	}
+	buf.Write(card.Bytes())
+	return absoluteURLs(buf.String(), "https://"+node.Tree.Domain+"/")
+}
+
+// mediaEnclosures finds the audio and video embedded in a post. The audio of a podcast episode comes first.
+func (node *ConfigNode) mediaEnclosures() []enclosure {
+	out := make([]enclosure, 0)
+	if node.Episode != nil {
+		out = append(out, enclosure{
+			URL:    node.Tree.baseURL() + node.Episode.Audio,
+			Type:   node.Episode.MIMEType,
+			Length: node.Episode.Size,
+		})
+	}
+	doc, _, err := node.parsedDocument()
+	if err != nil {
+		return out
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		destination, mimeType, ok := wwExt.MediaInfo(n)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		enc := enclosure{URL: destination, Type: mimeType}
+		if !strings.Contains(destination, "://") {
+			local := strings.TrimLeft(destination, "/")
+			enc.URL = "https://" + node.Tree.Domain + "/" + local
+			if st, err := os.Stat(local); err == nil {
+				enc.Length = st.Size()
+			}
+		}
+		out = append(out, enc)
+		return ast.WalkContinue, nil
+	})
+	return out
+}
@@ -494,6 +494,11 @@ func BuildGallery(node *ConfigNode) error {
 			richImages[img.Filename] = img
 		}
 	}
+	if themed, ok := node.themedGallery(pairs, richImages, bcHTML); ok {
+		node.HTML = themed
+		node.StructuredData = append(node.StructuredData, node.galleryStructuredData(structuredData))
+		return nil
+	}
 
 	for _, col := range grid {
 		galleryCol := galleryRow.AppendNew("div", Class("gallery-col"))
@@ -513,12 +518,7 @@ func BuildGallery(node *ConfigNode) error {
 		}
 	}
 	node.HTML = main
-	imgSD, err := json.MarshalIndent(structuredData, "", "    ")
-	if err != nil {
-		log.Println(err.Error())
-	} else {
-		node.StructuredData = append(node.StructuredData, string(imgSD))
-	}
+	node.StructuredData = append(node.StructuredData, node.galleryStructuredData(structuredData))
 
 	return nil
 }
@@ -35,39 +35,143 @@ package wyweb
 
 import (
 	"bytes"
-	"fmt"
-	"os"
+	"html"
 	"path/filepath"
-	"sort"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/yuin/goldmark/ast"
+
+	wwExt "wyweb.site/extensions"
 )
 
-func (node *ConfigNode) buildSitemap(urlset *HTMLElement, baseURL string) {
-	url := urlset.AppendNew("url")
-	url.AppendNew("loc").AppendText(baseURL + node.Path)
-	if !node.Updated.IsZero() {
-		url.AppendNew("lastmod").AppendText((*node).Updated.Format(time.DateOnly))
+// The sitemap protocol allows at most 50,000 URLs in a single file.
+const maxSitemapURLs = 50000
+
+var sitemapNameRegex = regexp.MustCompile(`^sitemap(\.[a-z0-9-]+)?(-[0-9]+)?\.xml$`)
+
+// sitemapName names a sitemap file. Translations have their own sitemaps, e.g. sitemap.es.xml, and a sitemap too
+// large for one file is split into parts such as sitemap-2.xml.
+func sitemapName(lang string, part string) string {
+	name := "sitemap"
+	if lang != "" {
+		name += "." + lang
 	}
-	for _, child := range node.Children {
-		child.buildSitemap(urlset, baseURL)
+	if part != "" {
+		name += "-" + part
 	}
-	files, err := os.ReadDir(node.Path)
-	if err != nil {
-		return
+	return name + ".xml"
+}
+
+type sitemapVideo struct {
+	Title        string
+	Description  string
+	ContentLoc   string
+	ThumbnailLoc string
+}
+
+type sitemapURL struct {
+	Loc        string
+	LastMod    time.Time
+	Images     []string
+	Videos     []sitemapVideo
+	Alternates []LangAlternate
+}
+
+// absoluteURL returns the absolute form of a URL that is either already absolute or relative to the document root.
+// Links to local files are rewritten to be relative to the document root, so anything else is not a local file and
+// results in an empty string.
+func absoluteURL(dest string, baseURL string) string {
+	if strings.Contains(dest, "://") {
+		return dest
+	}
+	if !strings.HasPrefix(dest, "/") {
+		return ""
 	}
-	for _, file := range files {
-		name := file.Name()
-		ext := strings.ToLower(filepath.Ext(name))
-		switch ext {
-		case ".jpg", ".jpeg", ".gif", ".png", ".svg", ".webp", ".tiff":
-			path := filepath.Join(node.Path, name)
-			url.AppendNew("image:image").AppendNew("image:loc").AppendText(baseURL + path)
+	return baseURL + strings.TrimLeft(dest, "/")
+}
+
+// sitemapMedia finds the images and videos of a page. Posts are searched for embedded media, and galleries list
+// their gallery items.
+func (node *ConfigNode) sitemapMedia(baseURL string) ([]string, []sitemapVideo) {
+	images := make([]string, 0)
+	videos := make([]sitemapVideo, 0)
+	switch node.NodeKind {
+	case WWGALLERY:
+		for _, img := range node.Images {
+			images = append(images, baseURL+filepath.Join(node.Path, img.Filename))
+		}
+	case WWPOST, WWRECIPE, WWPAGE:
+		doc, _, err := node.parsedDocument()
+		if err != nil {
+			return images, videos
+		}
+		videoSources := make([]string, 0)
+		ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			if img, ok := n.(*ast.Image); ok {
+				if loc := absoluteURL(string(img.Destination), baseURL); loc != "" {
+					images = append(images, loc)
+				}
+			} else if dest, mimeType, ok := wwExt.MediaInfo(n); ok && strings.HasPrefix(mimeType, "video/") {
+				if loc := absoluteURL(dest, baseURL); loc != "" {
+					videoSources = append(videoSources, loc)
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+		// Search engines require a thumbnail, for which the first image of the post is the best we can do, followed by
+		// the image of the page or of the site. Videos without any are left out.
+		var thumbnail string
+		if len(images) > 0 {
+			thumbnail = images[0]
+		} else {
+			thumbnail = node.socialImage().URL
+		}
+		if thumbnail == "" {
+			return images, videos
+		}
+		for _, src := range videoSources {
+			videos = append(videos, sitemapVideo{
+				Title:        node.Title,
+				Description:  node.Description,
+				ContentLoc:   src,
+				ThumbnailLoc: thumbnail,
+			})
 		}
 	}
+	return images, videos
 }
 
-func (tree *ConfigTree) MakeSitemap() {
+// Drafts and protected pages are left out of the sitemap along with everything beneath them. Pages that robots may not
+// index are left out, but their children are not unless they inherit the same setting. If lang is given, only the pages
+// translated into it are listed, in their translation.
+func (node *ConfigNode) collectSitemapURLs(baseURL string, lang string, urls *[]sitemapURL) {
+	if node.Draft || node.Protected {
+		return
+	}
+	if node.Robots.Indexable() && (lang == "" || node.HasLanguage(lang)) {
+		page := node.Variant(lang)
+		entry := sitemapURL{Loc: page.pageURL(), LastMod: node.Updated, Alternates: node.Alternates()}
+		entry.Images, entry.Videos = page.sitemapMedia(baseURL)
+		*urls = append(*urls, entry)
+	}
+	keys := make([]string, 0, len(node.Children))
+	for key := range node.Children {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		node.Children[key].collectSitemapURLs(baseURL, lang, urls)
+	}
+}
+
+func renderURLSet(urls []sitemapURL) []byte {
 	var sitemapXML bytes.Buffer
 	sitemapXML.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>`)
 	sitemapXML.WriteByte('\n')
@@ -76,119 +180,94 @@ func (tree *ConfigTree) MakeSitemap() {
 		"xmlns:image": "http://www.google.com/schemas/sitemap-image/1.1",
 		"xmlns:video": "http://www.google.com/schemas/sitemap-video/1.1",
 	})
-	baseURL := "https://" + tree.Domain + "/"
-	tree.Root.buildSitemap(urlset, baseURL)
-	RenderHTML(urlset, &sitemapXML)
-	sitemapFile, err := os.Create("sitemap.xml")
-	if err != nil && !os.IsExist(err) {
-		fmt.Printf("%+v\n", err)
-		return
+	for _, u := range urls {
+		if len(u.Alternates) > 0 {
+			urlset.Attributes["xmlns:xhtml"] = "http://www.w3.org/1999/xhtml"
+		}
+		url := urlset.AppendNew("url")
+		url.AppendNew("loc").Compact().AppendText(html.EscapeString(u.Loc))
+		if !u.LastMod.IsZero() {
+			url.AppendNew("lastmod").AppendText(u.LastMod.Format(time.DateOnly))
+		}
+		for _, alt := range u.Alternates {
+			url.AppendNew("xhtml:link", map[string]string{
+				"rel":      "alternate",
+				"hreflang": alt.Lang,
+				"href":     alt.URL,
+			}).SetSelfClosing(true)
+		}
+		for _, img := range u.Images {
+			url.AppendNew("image:image").AppendNew("image:loc").Compact().AppendText(html.EscapeString(img))
+		}
+		for _, v := range u.Videos {
+			video := url.AppendNew("video:video")
+			video.AppendNew("video:thumbnail_loc").Compact().AppendText(html.EscapeString(v.ThumbnailLoc))
+			video.AppendNew("video:title").AppendText(html.EscapeString(v.Title))
+			video.AppendNew("video:description").AppendText(html.EscapeString(v.Description))
+			video.AppendNew("video:content_loc").Compact().AppendText(html.EscapeString(v.ContentLoc))
+		}
 	}
-	defer sitemapFile.Close()
-	sitemapFile.Write(sitemapXML.Bytes())
+	RenderHTML(urlset, &sitemapXML)
+	return sitemapXML.Bytes()
 }
 
-func (node *ConfigNode) MakeRSS() []Listable {
-	if !(node.NodeKind == WWGALLERY || node.NodeKind == WWLISTING) {
-		return nil
-	}
-	path := filepath.Join(node.RealPath, "rssfeed.xml")
-	rssFile, err := os.Create(path)
-	if err != nil && !os.IsExist(err) {
-		fmt.Printf("%+v\n", err)
-		return nil
-	}
-	defer rssFile.Close()
-	var rssXML bytes.Buffer
-	rssXML.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>`)
-	rssXML.WriteByte('\n')
-	baseURL := "https://" + node.Tree.Domain + "/"
-	feed := NewHTMLElement("rss", map[string]string{
-		"version":    "2.0",
-		"xmlns:atom": "http://www.w3.org/2005/Atom",
+func renderSitemapIndex(baseURL string, names []string, lastmod time.Time) []byte {
+	var indexXML bytes.Buffer
+	indexXML.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>`)
+	indexXML.WriteByte('\n')
+	index := NewHTMLElement("sitemapindex", map[string]string{
+		"xmlns": "http://www.sitemaps.org/schemas/sitemap/0.9",
 	})
-	channel := feed.AppendNew("channel")
-	channel.AppendNew("title").AppendText(node.Title)
-	channel.AppendNew("description").AppendText(node.Description)
-	channel.AppendNew("link").AppendText(baseURL + node.Path)
-	channel.AppendNew("copyright").AppendText(node.Copyright)
-	channel.AppendNew("lastBuildDate").AppendText(time.Now().Format(time.RFC1123Z))
-	pubDate, _ := node.getMostRecentDates()
-	channel.AppendNew("pubDate").AppendText(pubDate.Format(time.RFC1123Z))
-	channel.AppendNew("ttl").AppendText("60")
-	channel.AppendNew("atom:link", Href(baseURL+path), map[string]string{"rel": "self", "type": "application/rss+xml"}).SetSelfClosing(true)
-
-	children := make([]Listable, 0)
-	switch node.NodeKind {
-	case WWLISTING:
-		for _, child := range node.Children {
-			children = append(children, child)
-		}
-	case WWGALLERY:
-		for _, child := range node.Images {
-			children = append(children, &child)
+	for _, name := range names {
+		sitemap := index.AppendNew("sitemap")
+		sitemap.AppendNew("loc").Compact().AppendText(baseURL + name)
+		if !lastmod.IsZero() {
+			sitemap.AppendNew("lastmod").AppendText(lastmod.Format(time.DateOnly))
 		}
 	}
-	sort.Slice(children, func(i, j int) bool {
-		return children[i].GetDate().After(children[j].GetDate())
-	})
-	for _, child := range children {
-		channel.Append(child.AsRSSItem())
-	}
-	RenderHTML(feed, &rssXML)
-	rssFile.Write(rssXML.Bytes())
-	return children
+	RenderHTML(index, &indexXML)
+	return indexXML.Bytes()
 }
 
-func (tree *ConfigTree) MakeRSS() {
-	path := "rssfeed.xml"
-	rssFile, err := os.Create(path)
-	if err != nil && !os.IsExist(err) {
-		fmt.Printf("%+v\n", err)
-		return
-	}
-	defer rssFile.Close()
-	var rssXML bytes.Buffer
-	rssXML.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>`)
-	rssXML.WriteByte('\n')
+// MakeSitemap builds the sitemap of the whole site and keeps it in memory. If there are too many URLs for a single
+// sitemap, sitemap.xml becomes a sitemap index pointing to sitemap-1.xml, sitemap-2.xml, and so on. Each language the
+// site is translated into gets a sitemap of its own in the same way, e.g. sitemap.es.xml.
+func (tree *ConfigTree) MakeSitemap() {
 	baseURL := "https://" + tree.Domain + "/"
-	feed := NewHTMLElement("rss", map[string]string{
-		"version":    "2.0",
-		"xmlns:atom": "http://www.w3.org/2005/Atom",
-	})
-	channel := feed.AppendNew("channel")
-	title := tree.Domain
-	if tree.Root.Title != "" {
-		title = tree.Root.Title
+	sitemaps := make(map[string][]byte)
+	langs := []string{""}
+	if len(tree.Languages) > 1 {
+		langs = append(langs, tree.Languages[1:]...)
 	}
-	channel.AppendNew("title").AppendText(title)
-	channel.AppendNew("description").AppendText(tree.Root.Description)
-	channel.AppendNew("link").AppendText(baseURL)
-	channel.AppendNew("copyright").AppendText(tree.Root.Copyright)
-	channel.AppendNew("lastBuildDate").AppendText(time.Now().Format(time.RFC1123Z))
-	pubDate, _ := tree.Root.getMostRecentDates()
-	channel.AppendNew("pubDate").AppendText(pubDate.Format(time.RFC1123Z))
-	channel.AppendNew("ttl").AppendText("60")
-	channel.AppendNew("atom:link", Href(baseURL+path), map[string]string{"rel": "self", "type": "application/rss+xml"}).SetSelfClosing(true)
-
-	items := make([]Listable, 0)
-	var dft func(*ConfigNode)
-	dft = func(node *ConfigNode) {
-		temp := node.MakeRSS()
-		if temp != nil {
-			items = append(items, temp...)
+	for _, lang := range langs {
+		urls := make([]sitemapURL, 0)
+		tree.Root.collectSitemapURLs(baseURL, lang, &urls)
+		if len(urls) <= maxSitemapURLs {
+			sitemaps[sitemapName(lang, "")] = renderURLSet(urls)
+			continue
 		}
-		for _, child := range node.Children {
-			dft(child)
+		names := make([]string, 0)
+		for start := 0; start < len(urls); start += maxSitemapURLs {
+			end := min(start+maxSitemapURLs, len(urls))
+			name := sitemapName(lang, strconv.Itoa(len(names)+1))
+			names = append(names, name)
+			sitemaps[name] = renderURLSet(urls[start:end])
 		}
+		lastmod, _ := tree.Root.getMostRecentDates()
+		sitemaps[sitemapName(lang, "")] = renderSitemapIndex(baseURL, names, lastmod)
 	}
-	dft(tree.Root)
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].GetDate().After(items[j].GetDate())
-	})
-	for _, item := range items {
-		channel.Append(item.AsRSSItem())
+	tree.Lock()
+	tree.sitemaps = sitemaps
+	tree.Unlock()
+}
+
+// GetSitemap returns the sitemap or sitemap index with the given file name.
+func (tree *ConfigTree) GetSitemap(name string) ([]byte, bool) {
+	if !sitemapNameRegex.MatchString(name) {
+		return nil, false
 	}
-	RenderHTML(feed, &rssXML)
-	rssFile.Write(rssXML.Bytes())
+	tree.RLock()
+	defer tree.RUnlock()
+	sitemap, ok := tree.sitemaps[name]
+	return sitemap, ok
 }
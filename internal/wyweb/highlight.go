@@ -0,0 +1,185 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"cmp"
+	"html"
+	"log"
+	"slices"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	gmUtil "github.com/yuin/goldmark/util"
+)
+
+// Styles of highlighted code used when no wyweb file chooses others.
+const (
+	DefaultCodeStyle      = "catppuccin-mocha"
+	DefaultPrintCodeStyle = "algol"
+)
+
+func (h WWHighlight) IsZero() bool {
+	return h.Light == "" && h.Dark == "" && h.Print == "" && h.LineNumbers == nil
+}
+
+// inherit fills in the settings h leaves unset from those of parent.
+func (h *WWHighlight) inherit(parent WWHighlight) {
+	h.Light = cmp.Or(h.Light, parent.Light)
+	h.Dark = cmp.Or(h.Dark, parent.Dark)
+	h.Print = cmp.Or(h.Print, parent.Print)
+	if h.LineNumbers == nil {
+		h.LineNumbers = parent.LineNumbers
+	}
+}
+
+// ShowLineNumbers reports whether the lines of code blocks are numbered. They are unless turned off.
+func (h WWHighlight) ShowLineNumbers() bool {
+	return h.LineNumbers == nil || *h.LineNumbers
+}
+
+// codeStyleSheet is a chroma style and the media it applies to.
+type codeStyleSheet struct {
+	Role  string
+	Style string
+	Media string
+}
+
+// key is the name the style sheet is registered under in ConfigTree.Resources. The media a style applies to is
+// part of it, since the light style is used for every screen unless there is a dark one.
+func (s codeStyleSheet) key() string {
+	return "highlight-" + s.Role + "-" + s.Style
+}
+
+// styleSheets gives the chroma styles chosen by h. A dark style applies to screens set to prefer dark colours, and
+// the light style to the others.
+func (h WWHighlight) styleSheets() []codeStyleSheet {
+	light := cmp.Or(h.Light, DefaultCodeStyle)
+	out := make([]codeStyleSheet, 0, 3)
+	if h.Dark == "" {
+		out = append(out, codeStyleSheet{"screen", light, "screen"})
+	} else {
+		out = append(out,
+			codeStyleSheet{"light", light, "screen and (prefers-color-scheme: light)"},
+			codeStyleSheet{"dark", h.Dark, "screen and (prefers-color-scheme: dark)"},
+		)
+	}
+	return append(out, codeStyleSheet{"print", cmp.Or(h.Print, DefaultPrintCodeStyle), "print"})
+}
+
+// registerHighlightStyles adds the style sheets chosen by h to the site's resources, unless they are there already.
+func (tree *ConfigTree) registerHighlightStyles(h WWHighlight) {
+	formatter := chromahtml.New(chromahtml.WithClasses(true))
+	for _, sheet := range h.styleSheets() {
+		if _, ok := tree.GetResource(sheet.key()); ok {
+			continue
+		}
+		style, ok := styles.Registry[sheet.Style]
+		if !ok {
+			log.Printf("WARN: there is no highlighting style called %s. Using %s instead.\n", sheet.Style, styles.Fallback.Name)
+			style = styles.Fallback
+		}
+		var css bytes.Buffer
+		formatter.WriteCSS(&css, style)
+		tree.SetResource(sheet.key(), Resource{
+			Type:       "style",
+			Method:     "raw",
+			Value:      css.String(),
+			Attributes: map[string]string{"media": sheet.Media},
+		})
+	}
+}
+
+// highlightResources gives the style sheets of highlighted code for the page if it has any code blocks.
+func (node *ConfigNode) highlightResources() []string {
+	if !node.features.Code {
+		return nil
+	}
+	out := make([]string, 0, 3)
+	for _, sheet := range node.Highlight.styleSheets() {
+		if !slices.Contains(node.excluded, sheet.key()) {
+			out = append(out, sheet.key())
+		}
+	}
+	return out
+}
+
+// highlightingOptions configures the highlighting of code blocks, numbering their lines if lineNumbers is set.
+// Single blocks may still set hl_lines, linenos, linenostart, and filename in braces after their language.
+func highlightingOptions(lineNumbers bool) []highlighting.Option {
+	return []highlighting.Option{
+		highlighting.WithFormatOptions(
+			chromahtml.WithLineNumbers(lineNumbers),
+			chromahtml.WithClasses(true),
+		),
+		highlighting.WithWrapperRenderer(wrapCodeBlock),
+	}
+}
+
+// codeFilename is the attribute of a fenced code block naming the file it shows, as in ```go {filename="main.go"}
+var codeFilename = []byte("filename")
+
+// wrapCodeBlock puts a code block naming a file in a figure captioned with the name. Code that could not be
+// highlighted is given the pre and code elements it has without a wrapper.
+func wrapCodeBlock(w gmUtil.BufWriter, c highlighting.CodeBlockContext, entering bool) {
+	var filename []byte
+	if attrs := c.Attributes(); attrs != nil {
+		if value, ok := attrs.Get(codeFilename); ok {
+			filename, _ = value.([]byte)
+		}
+	}
+	if !entering {
+		if !c.Highlighted() {
+			w.WriteString("</code></pre>\n")
+		}
+		if filename != nil {
+			w.WriteString("</figure>\n")
+		}
+		return
+	}
+	if filename != nil {
+		w.WriteString(`<figure class="code-block"><figcaption class="code-filename">`)
+		w.WriteString(html.EscapeString(string(filename)))
+		w.WriteString("</figcaption>")
+	}
+	if !c.Highlighted() {
+		w.WriteString("<pre><code")
+		if lang, ok := c.Language(); ok {
+			w.WriteString(` class="language-` + html.EscapeString(string(lang)) + `"`)
+		}
+		w.WriteString(">")
+	}
+}
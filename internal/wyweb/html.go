@@ -36,6 +36,7 @@ package wyweb
 import (
 	"bytes"
 	"fmt"
+	"html"
 	"slices"
 	"strings"
 )
@@ -63,6 +64,7 @@ type HTMLElement struct {
 	Attributes  map[string]string
 	Children    []*HTMLElement
 	indent      bool
+	compact     bool
 	SelfClosing bool
 }
 
@@ -94,6 +96,18 @@ func (e *HTMLElement) NoIndent() {
 	e.indent = false
 }
 
+// Compact elements are rendered on a single line regardless of the length of their text. This matters for XML formats
+// in which surrounding whitespace would change the meaning of the content, such as ids and URLs.
+func (e *HTMLElement) Compact() *HTMLElement {
+	e.compact = true
+	for _, child := range e.Children {
+		if child.Tag == "" {
+			child.compact = true
+		}
+	}
+	return e
+}
+
 func (e *HTMLElement) SetSelfClosing(sc bool) {
 	e.SelfClosing = sc
 }
@@ -142,6 +156,7 @@ func (e *HTMLElement) AppendText(text string) *HTMLElement {
 		Attributes: nil,
 		Children:   nil,
 		indent:     true,
+		compact:    e.compact,
 	}
 	e.Children = append(e.Children, elem)
 	return elem
@@ -151,6 +166,9 @@ func isShort(elem *HTMLElement) (bool, int) {
 	if elem == nil {
 		return false, 0
 	}
+	if elem.compact {
+		return true, 0
+	}
 	if len(elem.Children) > 1 {
 		return false, 0
 	}
@@ -179,14 +197,16 @@ func openTag(elem *HTMLElement, depth int) []byte {
 		if value != "" {
 			out.WriteByte('=')
 			out.WriteByte('"')
-			out.WriteString(value)
+			out.WriteString(html.EscapeString(value))
 			out.WriteByte('"')
 		}
 	}
 	if elem.SelfClosing {
 		out.WriteString(" /")
 	}
-	if slices.Contains(voidElements, elem.Tag) {
+	if elem.compact {
+		out.WriteByte('>')
+	} else if slices.Contains(voidElements, elem.Tag) {
 		out.WriteString(">\n")
 	} else if short, textlen := isShort(elem); short && textlen < 32 {
 		out.WriteByte('>')
@@ -313,6 +333,23 @@ func (e *HTMLElement) RemoveNode(target *HTMLElement) bool {
 	return false
 }
 
+// Clone makes a deep copy of the element, so that a cached page can be altered for a single response.
+func (e *HTMLElement) Clone() *HTMLElement {
+	if e == nil {
+		return nil
+	}
+	out := *e
+	out.Attributes = make(map[string]string, len(e.Attributes))
+	for key, value := range e.Attributes {
+		out.Attributes[key] = value
+	}
+	out.Children = make([]*HTMLElement, len(e.Children))
+	for idx, child := range e.Children {
+		out.Children[idx] = child.Clone()
+	}
+	return &out
+}
+
 func printHTML(elem *HTMLElement) {
 	var buf bytes.Buffer
 	RenderHTML(elem, &buf)
@@ -0,0 +1,329 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A page in another language, as listed in the head of each of its translations.
+type LangAlternate struct {
+	Lang string
+	URL  string
+}
+
+// DefaultLanguage is the first of the site's languages, in which pages are written unless they say otherwise. It is
+// empty if the site does not name its languages.
+func (tree *ConfigTree) DefaultLanguage() string {
+	if len(tree.Languages) == 0 {
+		return ""
+	}
+	return tree.Languages[0]
+}
+
+// IsTranslation reports whether lang is one of the site's languages other than the default.
+func (tree *ConfigTree) IsTranslation(lang string) bool {
+	return lang != "" && lang != tree.DefaultLanguage() && slices.Contains(tree.Languages, lang)
+}
+
+// Language is the language of the page, falling back to English if the site does not name its languages.
+func (node *ConfigNode) Language() string {
+	if node.Lang != "" {
+		return node.Lang
+	}
+	if lang := node.Tree.DefaultLanguage(); lang != "" {
+		return lang
+	}
+	return "en"
+}
+
+// htmlLang is the lang attribute of the page's document, which is left out if the site does not name its languages.
+func (node *ConfigNode) htmlLang() string {
+	if len(node.Tree.Languages) == 0 {
+		return ""
+	}
+	return node.Language()
+}
+
+// langQuery is the query that selects the language of a translated page.
+func (node *ConfigNode) langQuery() string {
+	if node.Lang == "" {
+		return ""
+	}
+	return "?lang=" + url.QueryEscape(node.Lang)
+}
+
+// original is the page a translation was made from, or the page itself.
+func (node *ConfigNode) original() *ConfigNode {
+	if node.variantOf != nil {
+		return node.variantOf
+	}
+	return node
+}
+
+// titleIn gives the title of the page in the given language, or its own title if it has not been translated.
+func (node *ConfigNode) titleIn(lang string) string {
+	if title := node.Translations[lang]["title"]; title != "" {
+		return title
+	}
+	return node.Title
+}
+
+// variantIndexPath names the translation of a markdown file, e.g. article.es.md for article.md.
+func variantIndexPath(index string, lang string) string {
+	ext := filepath.Ext(index)
+	if ext != ".md" {
+		return index + "." + lang
+	}
+	return strings.TrimSuffix(index, ext) + "." + lang + ext
+}
+
+// translatedIndex finds the markdown of the page in the given language. It is either named with an index.<lang>
+// field or sits next to the page's own markdown, as article.<lang>.md does next to article.md.
+func (node *ConfigNode) translatedIndex(lang string) string {
+	if index := node.Translations[lang]["index"]; index != "" {
+		for _, candidate := range []string{
+			index,
+			filepath.Join(node.RealPath, index),
+			filepath.Join(filepath.Dir(node.RealPath), index),
+		} {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+		return ""
+	}
+	if node.Index == "" {
+		return ""
+	}
+	index := variantIndexPath(node.Index, lang)
+	if _, err := os.Stat(index); err != nil {
+		return ""
+	}
+	return index
+}
+
+// HasLanguage reports whether the page has been translated into lang, either by its fields or by its markdown. The
+// root stands for the whole site, so it has every language of the site.
+func (node *ConfigNode) HasLanguage(lang string) bool {
+	node = node.original()
+	if !node.Tree.IsTranslation(lang) {
+		return false
+	}
+	if node == node.Tree.Root || len(node.Translations[lang]) > 0 {
+		return true
+	}
+	return node.translatedIndex(lang) != ""
+}
+
+// Variant gives the page in the requested language. The page itself is given for its default language or if it has
+// not been translated.
+func (node *ConfigNode) Variant(lang string) *ConfigNode {
+	node = node.original()
+	lang = strings.ToLower(lang)
+	if !node.HasLanguage(lang) {
+		return node
+	}
+	node.Lock()
+	defer node.Unlock()
+	if variant, ok := node.variants[lang]; ok {
+		return variant
+	}
+	variant := node.translated(lang)
+	if node.variants == nil {
+		node.variants = make(map[string]*ConfigNode)
+	}
+	node.variants[lang] = variant
+	return variant
+}
+
+// forgetVariants discards the translations of a page so that they are made again from the page as it is now.
+func (node *ConfigNode) forgetVariants() {
+	node.Lock()
+	node.variants = nil
+	node.Unlock()
+}
+
+// translated makes a copy of the page with its translated fields and markdown. The copy shares its place in the tree
+// with the page, but has its own rendered markup.
+func (node *ConfigNode) translated(lang string) *ConfigNode {
+	out := newConfigNode()
+	out.PageData = node.PageData
+	copyHeadData(&out.HeadData, &node.HeadData)
+	out.id = node.id
+	out.resolved = true
+	out.NodeKind = node.NodeKind
+	out.Children = node.Children
+	out.LocalResources = node.LocalResources
+	out.Data = node.Data
+	out.Parent = node.Parent
+	out.Index = node.Index
+	out.TagDB = node.TagDB
+	out.Tags = node.Tags
+	out.Series = node.Series
+	out.SeriesPrev = node.SeriesPrev
+	out.SeriesNext = node.SeriesNext
+	out.Podcast = node.Podcast
+	out.Episode = node.Episode
+	out.Recipe = node.Recipe
+	out.Page = node.Page
+	out.Tree = node.Tree
+	out.Preview = node.Preview
+	out.RealPath = node.RealPath
+	out.Images = node.Images
+	out.LastRead = node.LastRead
+	out.Lang = lang
+	out.variantOf = node
+	for key, kind := range node.Dependencies {
+		out.Dependencies[key] = kind
+	}
+
+	fields := node.Translations[lang]
+	if index := node.translatedIndex(lang); index != "" {
+		out.Index = index
+		node.Dependencies[index] = KindFileEmbed
+		// The title and preview of the translated markdown take the place of those of the original.
+		out.Title = fields["title"]
+		out.Preview = fields["preview"]
+		if out.Title == "" || out.Preview == "" {
+			if mdfile, err := os.ReadFile(index); err == nil {
+				if out.Title == "" {
+					GetTitleFromMarkdown(out, mdfile, nil)
+				}
+				if out.Preview == "" {
+					GetPreviewFromMarkdown(out, mdfile, nil)
+				}
+			}
+		}
+	}
+	if title := fields["title"]; title != "" {
+		out.Title = title
+	}
+	if description := fields["description"]; description != "" {
+		out.Description = description
+	}
+	if image := fields["image"]; image != "" {
+		out.Image = image
+	}
+	if preview := fields["preview"]; preview != "" {
+		out.Preview = preview
+	}
+	for _, link := range []*WWNavLink{&out.Prev, &out.Next, &out.Up, &out.SeriesPrev, &out.SeriesNext} {
+		if target, err := node.Tree.Search(strings.TrimPrefix(link.Path, "/")); err == nil && link.Text != "" {
+			link.Text = target.titleIn(lang)
+		}
+	}
+	return out
+}
+
+// Alternates lists the page in each language it has been translated into, starting with the default language. It
+// is empty if the page has not been translated.
+func (node *ConfigNode) Alternates() []LangAlternate {
+	original := node.original()
+	tree := node.Tree
+	out := make([]LangAlternate, 0)
+	for _, lang := range tree.Languages[min(1, len(tree.Languages)):] {
+		if original.HasLanguage(lang) {
+			out = append(out, LangAlternate{Lang: lang, URL: original.pageURL() + "?lang=" + url.QueryEscape(lang)})
+		}
+	}
+	if len(out) == 0 {
+		return out
+	}
+	return append([]LangAlternate{
+		{Lang: tree.DefaultLanguage(), URL: original.pageURL()},
+		{Lang: "x-default", URL: original.pageURL()},
+	}, out...)
+}
+
+var hrefRegex = regexp.MustCompile(`href="([^"]*)"`)
+
+// localizedURL adds the language of the page to a link to another page of the site that has been translated into the
+// same language, so that readers stay in the language they chose.
+func (node *ConfigNode) localizedURL(href string) string {
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "" || u.Host != "" || href == "" || href[0] == '#' || strings.Contains(u.RawQuery, "lang=") {
+		return href
+	}
+	base := &url.URL{Path: "/" + node.Path}
+	target := node.Tree.Root
+	if path := strings.Trim(base.ResolveReference(u).Path, "/"); path != "" {
+		target, err = node.Tree.Search(path)
+	}
+	if err != nil || !target.HasLanguage(node.Lang) {
+		return href
+	}
+	fragment := ""
+	if idx := strings.IndexByte(href, '#'); idx >= 0 {
+		href, fragment = href[:idx], href[idx:]
+	}
+	sep := "?"
+	if u.RawQuery != "" {
+		sep = "&"
+	}
+	return href + sep + "lang=" + url.QueryEscape(node.Lang) + fragment
+}
+
+// LocalizeLinks rewrites the links of markup made for a translated page, both those WyWeb generates and those in its
+// markdown.
+func (node *ConfigNode) LocalizeLinks(elem *HTMLElement) {
+	if node.Lang == "" || elem == nil {
+		return
+	}
+	var walk func(*HTMLElement)
+	walk = func(elem *HTMLElement) {
+		if elem == nil {
+			return
+		}
+		if href, ok := elem.Attributes["href"]; ok {
+			elem.Attributes["href"] = node.localizedURL(href)
+		}
+		if elem.Content != "" && strings.Contains(elem.Content, "href=") {
+			elem.Content = hrefRegex.ReplaceAllStringFunc(elem.Content, func(match string) string {
+				href := html.UnescapeString(hrefRegex.FindStringSubmatch(match)[1])
+				return `href="` + html.EscapeString(node.localizedURL(href)) + `"`
+			})
+		}
+		for _, child := range elem.Children {
+			walk(child)
+		}
+	}
+	walk(elem)
+}
@@ -65,9 +65,9 @@ func MagicListing(parent *ConfigNode, name string) *ConfigNode {
 	return out
 }
 
-func makeTagContainer(tags []string) *HTMLElement {
+func makeTagContainer(node *ConfigNode, tags []string) *HTMLElement {
 	tagcontainer := NewHTMLElement("div", Class("tag-container"))
-	tagcontainer.AppendText("Tags")
+	tagcontainer.AppendText(node.T("Tags"))
 	taglist := tagcontainer.AppendNew("div", Class("tag-list"))
 	for _, tag := range tags {
 		taglist.AppendNew("a", Class("tag-link"), Href("?tags="+url.QueryEscape(tag))).AppendText(tag)
@@ -95,8 +95,11 @@ func postToListItem(post *ConfigNode) *HTMLElement {
 		}
 		GetPreviewFromMarkdown(post, mdfile, nil)
 	}
+	if themed, ok := post.themedListItem(); ok {
+		return themed
+	}
 	listing.AppendNew("div", Class("preview")).AppendText(post.Preview)
-	listing.Append(makeTagContainer(post.Tags))
+	listing.Append(makeTagContainer(post, post.Tags))
 	return listing
 }
 
@@ -117,7 +120,7 @@ func galleryItemToListItem(item *RichImage) *HTMLElement {
 	infoContainer.AppendNew("span", Class("gallery-info-medium")).AppendText(item.Medium)
 	infoContainer.AppendNew("span", Class("gallery-info-location")).AppendText(item.Location)
 	infoContainer.AppendNew("span", Class("gallery-info-description")).AppendText(item.Description)
-	listing.Append(makeTagContainer(item.Tags))
+	listing.Append(makeTagContainer(item.ParentPage, item.Tags))
 	return listing
 }
 
@@ -125,7 +128,7 @@ func buildTagCloud(node *ConfigNode, cloud *HTMLElement, crumbs *HTMLElement) *H
 	body := NewHTMLElement("body")
 	header := body.AppendNew("header", Class("listing-header"))
 	header.Append(crumbs)
-	header.AppendNew("h1").AppendText("Tag Cloud")
+	header.AppendNew("h1").AppendText(node.T("Tag Cloud"))
 	page := body.AppendNew("article")
 	//header.AppendNew("div", Class("description")).AppendText(description)
 	page.Append(cloud)
@@ -148,7 +151,7 @@ func BuildTagListing(node *ConfigNode, taglist []string, crumbs *HTMLElement) *H
 			}
 		}
 		TagDB := node.Tree.TagDB
-		if node != node.Tree.Root {
+		if node.original() != node.Tree.Root {
 			TagDB = node.TagDB
 		}
 		for tag, items := range TagDB {
@@ -161,63 +164,89 @@ func BuildTagListing(node *ConfigNode, taglist []string, crumbs *HTMLElement) *H
 	}
 	listingData := make([]Listable, 0)
 	var msg bytes.Buffer
-	if node == node.Tree.Root {
+	if node.original() == node.Tree.Root {
 		for _, tag := range taglist {
 			listingData = util.ConcatUnique(listingData, node.Tree.GetItemsByTag(tag))
 		}
-		msg.WriteString(fmt.Sprintf("Items tagged with %v", taglist))
+		msg.WriteString(node.T("Items tagged with %v", taglist))
 	} else {
 		for _, tag := range taglist {
 			listingData = util.ConcatUnique(listingData, node.GetItemsByTag(tag))
 		}
-		msg.WriteString(fmt.Sprintf("Items in %s tagged with %v", node.Title, taglist))
+		msg.WriteString(node.T("Items in %s tagged with %v", node.Title, taglist))
 		msg.WriteString("\n<br>\n")
-		qs := url.Values(map[string][]string{"tags": taglist}).Encode()
+		query := url.Values(map[string][]string{"tags": taglist})
+		if node.Lang != "" {
+			query.Set("lang", node.Lang)
+		}
+		qs := query.Encode()
 		alltags := NewHTMLElement("a", Href("/tags?"+qs))
-		alltags.AppendText(fmt.Sprintf("All items tagged with %v", taglist))
+		alltags.AppendText(node.T("All items tagged with %v", taglist))
 		RenderHTML(alltags, &msg)
 	}
 	sort.Slice(listingData, func(i, j int) bool {
 		return listingData[i].GetDate().After(listingData[j].GetDate())
 	})
+	for idx, item := range listingData {
+		if post, ok := item.(*ConfigNode); ok {
+			listingData[idx] = post.Variant(node.Lang)
+		}
+	}
 	if crumbs == nil {
-		crumbs, _ = Breadcrumbs(nil, WWNavLink{Path: "/", Text: "Home"}, WWNavLink{Path: "", Text: "Tags"})
+		crumbs, _ = Breadcrumbs(nil, WWNavLink{Path: "/", Text: node.T("Home")}, WWNavLink{Path: "", Text: node.T("Tags")})
 	}
-	return BuildListing(listingData, crumbs, "Tags", msg.String())
+	return BuildListing(node, listingData, crumbs, node.T("Tags"), msg.String())
 }
 
 func BuildDirListing(node *ConfigNode) error {
 	children := make([]Listable, 0)
 	for _, child := range node.Children {
-		children = append(children, child)
+		if child.Draft || child.NodeKind == WWPAGE {
+			continue
+		}
+		children = append(children, child.Variant(node.Lang))
 	}
 	sort.Slice(children, func(i, j int) bool {
 		return children[i].GetDate().After(children[j].GetDate())
 	})
 	crumbs, bcSD := Breadcrumbs(node)
-	node.HTML = BuildListing(children, crumbs, node.Title, node.Description)
+	node.HTML = BuildListing(node, children, crumbs, node.Title, node.Description)
 	node.StructuredData = append(node.StructuredData, bcSD)
+	node.StructuredData = append(node.StructuredData, node.listingStructuredData(children))
 	return nil
 }
 
-func BuildListing(items []Listable, breadcrumbs *HTMLElement, title, description string) *HTMLElement {
+func BuildListing(node *ConfigNode, items []Listable, breadcrumbs *HTMLElement, title, description string) *HTMLElement {
+	if themed, ok := node.themedListing(items, breadcrumbs, title, description); ok {
+		return themed
+	}
 	body := NewHTMLElement("body")
 	header := body.AppendNew("header", Class("listing-header"))
 	page := body.AppendNew("article", Class("listing-container"))
 	header.Append(breadcrumbs)
 	header.AppendNew("h1").AppendText(title)
 	header.AppendNew("div", Class("description")).AppendText(description)
+	appendListItems(page, items)
+	return body
+}
+
+// BuildListItem renders an item as it appears in a listing, or gives nil if the item does not belong in one.
+func BuildListItem(item Listable) *HTMLElement {
+	switch t := item.(type) {
+	case *ConfigNode:
+		if t.isArticle() {
+			return postToListItem(t)
+		}
+	case *RichImage:
+		return galleryItemToListItem(t)
+	}
+	return nil
+}
+
+func appendListItems(page *HTMLElement, items []Listable) {
 	for _, item := range items {
-		switch t := item.(type) {
-		case *ConfigNode:
-			if t.NodeKind == WWPOST {
-				page.Append(postToListItem(t))
-			}
-		case *RichImage:
-			page.Append(galleryItemToListItem(t))
-		default:
-			continue
+		if elem := BuildListItem(item); elem != nil {
+			page.Append(elem)
 		}
 	}
-	return body
 }
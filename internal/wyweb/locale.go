@@ -0,0 +1,184 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The strings and date format of the markup WyWeb generates, for one language. Strings are looked up by their
+// English text, so anything missing from a catalog is shown in English.
+type Locale struct {
+	DateFormat  string            `yaml:"date_format,omitempty"`
+	Months      []string          `yaml:"months,omitempty"`
+	ShortMonths []string          `yaml:"short_months,omitempty"`
+	Strings     map[string]string `yaml:"strings,omitempty"`
+}
+
+const defaultDateFormat = "Jan _2, 2006"
+
+//go:embed locales/*.yaml
+var builtinLocales embed.FS
+
+// loadLocales reads the catalogs shipped with WyWeb and lays the site's own on top of them, string by string.
+func loadLocales(site map[string]Locale) map[string]*Locale {
+	out := make(map[string]*Locale)
+	files, _ := builtinLocales.ReadDir("locales")
+	for _, file := range files {
+		data, err := builtinLocales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			continue
+		}
+		var locale Locale
+		if err = yaml.Unmarshal(data, &locale); err != nil {
+			log.Printf("WARN: could not read the built-in locale %s: %s", file.Name(), err.Error())
+			continue
+		}
+		out[strings.TrimSuffix(file.Name(), ".yaml")] = &locale
+	}
+	for lang, custom := range site {
+		lang = strings.ToLower(lang)
+		locale, ok := out[lang]
+		if !ok {
+			locale = &Locale{}
+			out[lang] = locale
+		}
+		locale.merge(custom)
+	}
+	return out
+}
+
+func (l *Locale) merge(src Locale) {
+	if src.DateFormat != "" {
+		l.DateFormat = src.DateFormat
+	}
+	if len(src.Months) == 12 {
+		l.Months = src.Months
+	}
+	if len(src.ShortMonths) == 12 {
+		l.ShortMonths = src.ShortMonths
+	}
+	if l.Strings == nil {
+		l.Strings = make(map[string]string)
+	}
+	for key, value := range src.Strings {
+		l.Strings[key] = value
+	}
+}
+
+// T translates a string of the generated markup. Any further arguments are formatted into it as with fmt.Sprintf.
+func (l *Locale) T(text string, args ...interface{}) string {
+	if l != nil {
+		if translated, ok := l.Strings[text]; ok && translated != "" {
+			text = translated
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// FormatDate formats t with the date format of the locale. Since Go only knows the English names of the months, the
+// names are put in separately wherever the format asks for one.
+func (l *Locale) FormatDate(t time.Time) string {
+	layout := defaultDateFormat
+	if l != nil && l.DateFormat != "" {
+		layout = l.DateFormat
+	}
+	var out strings.Builder
+	for layout != "" {
+		idx := strings.Index(layout, "Jan")
+		if idx < 0 {
+			out.WriteString(t.Format(layout))
+			break
+		}
+		if idx > 0 {
+			out.WriteString(t.Format(layout[:idx]))
+			layout = layout[idx:]
+		}
+		if strings.HasPrefix(layout, "January") {
+			out.WriteString(l.month(t.Month(), false))
+			layout = layout[len("January"):]
+		} else {
+			out.WriteString(l.month(t.Month(), true))
+			layout = layout[len("Jan"):]
+		}
+	}
+	return out.String()
+}
+
+func (l *Locale) month(m time.Month, short bool) string {
+	names := []string(nil)
+	if l != nil {
+		names = l.Months
+		if short {
+			names = l.ShortMonths
+		}
+	}
+	if len(names) == 12 {
+		return names[m-1]
+	}
+	if short {
+		return m.String()[:3]
+	}
+	return m.String()
+}
+
+// Locale gives the catalog of a language, or nil if there is none, in which case everything is shown in English.
+func (tree *ConfigTree) Locale(lang string) *Locale {
+	return tree.locales[strings.ToLower(lang)]
+}
+
+// T translates a string of the generated markup into the language of the page.
+func (node *ConfigNode) T(text string, args ...interface{}) string {
+	return node.Tree.Locale(node.Language()).T(text, args...)
+}
+
+// FormatDate formats a date as it is shown on the page, in the page's language.
+func (node *ConfigNode) FormatDate(t time.Time) string {
+	return node.Tree.Locale(node.Language()).FormatDate(t)
+}
+
+// monthName gives the name of a month in the page's language, abbreviated if short is set.
+func (node *ConfigNode) monthName(m time.Month, short bool) string {
+	return node.Tree.Locale(node.Language()).month(m, short)
+}
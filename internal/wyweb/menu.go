@@ -0,0 +1,171 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// findPage looks up a page by its path from the document root, with or without leading and trailing slashes.
+func (tree *ConfigTree) findPage(path string) (*ConfigNode, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return tree.Root, nil
+	}
+	return tree.Search(path)
+}
+
+// checkMenu warns about the pages named by the menu that cannot be found. Each is reported when it goes missing rather
+// than every time the site changes.
+func (tree *ConfigTree) checkMenu() {
+	missing := make(map[string]bool)
+	var check func([]WWMenuEntry)
+	check = func(entries []WWMenuEntry) {
+		for _, entry := range entries {
+			if entry.Node != "" {
+				if _, err := tree.findPage(entry.Node); err != nil {
+					missing[entry.Node] = true
+					if !tree.menuMissing[entry.Node] {
+						log.Printf("WARN: the menu links to %s, which does not exist", entry.Node)
+					}
+				}
+			}
+			check(entry.Children)
+		}
+	}
+	check(tree.Menu)
+	tree.menuMissing = missing
+}
+
+// within reports whether node is section or lies beneath it.
+func (node *ConfigNode) within(section *ConfigNode) bool {
+	for temp := node; temp != nil; temp = temp.Parent {
+		if temp == section {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildMenu renders the site menu as seen from this page. The entries leading to the page, whether the page itself or
+// one of the sections it is in, are marked active. It is nil if the site has no menu or the page has turned it off.
+func (node *ConfigNode) BuildMenu() *HTMLElement {
+	if len(node.Tree.Menu) == 0 || (node.NodeKind == WWPAGE && !node.Page.ShowMenu()) {
+		return nil
+	}
+	list, _ := node.menuList(node.Tree.Menu, node.original())
+	if list == nil {
+		return nil
+	}
+	nav := NewHTMLElement("nav", Class("site-menu"), AriaLabel(node.T("Menu")))
+	nav.Append(list)
+	node.LocalizeLinks(nav)
+	return nav
+}
+
+func (node *ConfigNode) menuList(entries []WWMenuEntry, current *ConfigNode) (*HTMLElement, bool) {
+	list := NewHTMLElement("ul")
+	anyActive := false
+	for _, entry := range entries {
+		href := entry.Path
+		text := node.T(entry.Text)
+		var target *ConfigNode
+		if entry.Node != "" {
+			target, _ = node.Tree.findPage(entry.Node)
+			href = ""
+			if target != nil {
+				href = "/" + target.Path
+				if entry.Text == "" {
+					text = target.titleIn(node.Lang)
+				}
+			}
+		} else if strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//") {
+			target, _ = node.Tree.findPage(href)
+		}
+		var sublist *HTMLElement
+		active := false
+		if len(entry.Children) > 0 {
+			sublist, active = node.menuList(entry.Children, current)
+		}
+		// A page that has gone missing has already been warned about, and only its children are worth keeping.
+		if href == "" && sublist == nil {
+			continue
+		}
+		// Every page lies beneath the root, which is only active on its own.
+		exact := target != nil && target == current
+		active = active || exact || (target != nil && target != node.Tree.Root && current.within(target))
+		cls := "menu-item"
+		if active {
+			cls += " menu-active"
+			anyActive = true
+		}
+		item := list.AppendNew("li", Class(cls))
+		var label *HTMLElement
+		if href != "" {
+			label = item.AppendNew("a", Href(href))
+			if exact {
+				label.Attributes["aria-current"] = "page"
+			}
+		} else {
+			label = item.AppendNew("span")
+		}
+		if entry.Icon != "" {
+			icon := entry.Icon
+			if !strings.Contains(icon, "://") && !strings.HasPrefix(icon, "/") {
+				icon = "/" + icon
+			}
+			label.AppendNew("img", Class("menu-icon"), map[string]string{"src": icon, "alt": ""})
+		}
+		label.AppendText(text)
+		if sublist != nil {
+			item.Append(sublist)
+		}
+	}
+	if len(list.Children) == 0 {
+		return nil, false
+	}
+	return list, anyActive
+}
+
+// withMenu puts the menu at the top of a body without altering the body itself, which is cached.
+func withMenu(body *HTMLElement, menu *HTMLElement) *HTMLElement {
+	if body == nil || menu == nil {
+		return body
+	}
+	out := *body
+	out.Children = slices.Concat([]*HTMLElement{menu}, body.Children)
+	return &out
+}
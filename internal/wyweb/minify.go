@@ -0,0 +1,216 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"slices"
+	"strings"
+)
+
+// skipString returns the index of the quote that closes the string starting at src[start], or the end of src if it is
+// never closed.
+func skipString(src string, start int) int {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(src) - 1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// minifyCSS removes comments along with the whitespace CSS has no use for. Whitespace is only dropped next to
+// punctuation that cannot be part of a selector or value, so that descendant selectors and calc() keep their meaning.
+func minifyCSS(src string) string {
+	const spaceAfter = "{};,>~(:"
+	const spaceBefore = "{};,>~)!"
+	var out strings.Builder
+	var last byte
+	pendingSpace := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if c == '/' && i+1 < len(src) && src[i+1] == '*' {
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				break
+			}
+			i += end + 3
+			pendingSpace = true
+			continue
+		}
+		if isSpace(c) {
+			pendingSpace = true
+			continue
+		}
+		if pendingSpace && last != 0 && !strings.ContainsRune(spaceAfter, rune(last)) && !strings.ContainsRune(spaceBefore, rune(c)) &&
+			!(c == ':' && inDeclaration(src[i:])) {
+			out.WriteByte(' ')
+		}
+		pendingSpace = false
+		if c == '"' || c == '\'' {
+			end := skipString(src, i)
+			out.WriteString(src[i : end+1])
+			i = end
+			last = c
+			continue
+		}
+		if c == '}' && last == ';' {
+			trimmed := strings.TrimSuffix(out.String(), ";")
+			out.Reset()
+			out.WriteString(trimmed)
+		}
+		out.WriteByte(c)
+		last = c
+	}
+	return out.String()
+}
+
+// inDeclaration tells whether rest, the remainder of a stylesheet, starts inside a declaration rather than a selector,
+// where a space before a colon would mean something.
+func inDeclaration(rest string) bool {
+	end := strings.IndexAny(rest, "{;}")
+	return end < 0 || rest[end] != '{'
+}
+
+// Words after which a slash begins a regular expression rather than a division.
+var regexKeywords = []string{"return", "typeof", "case", "do", "else", "in", "of", "void", "delete", "throw", "new",
+	"instanceof", "yield", "await"}
+
+// minifyJS removes comments, indentation, blank lines, and repeated spaces. Line breaks are kept, since automatic
+// semicolon insertion may depend on them.
+func minifyJS(src string) string {
+	var out strings.Builder
+	var line strings.Builder
+	pendingSpace := false
+	flush := func() {
+		if text := strings.TrimSpace(line.String()); text != "" {
+			out.WriteString(text)
+			out.WriteByte('\n')
+		}
+		line.Reset()
+	}
+	// regexAllowed tells whether a slash at this point begins a regular expression, judging by what came before it.
+	regexAllowed := func() bool {
+		text := strings.TrimRight(line.String(), " \t")
+		if text == "" {
+			text = strings.TrimRight(out.String(), "\n")
+		}
+		if text == "" {
+			return true
+		}
+		last := text[len(text)-1]
+		if strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", rune(last)) {
+			return true
+		}
+		word := text[strings.LastIndexFunc(text, func(r rune) bool {
+			return !(r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		})+1:]
+		return slices.Contains(regexKeywords, word)
+	}
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end - 1
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+				break
+			}
+			if strings.Contains(src[i:i+end+4], "\n") {
+				flush()
+			} else {
+				pendingSpace = true
+			}
+			i += end + 3
+		case c == '\n':
+			flush()
+			pendingSpace = false
+		case isSpace(c):
+			pendingSpace = true
+		default:
+			if pendingSpace {
+				line.WriteByte(' ')
+				pendingSpace = false
+			}
+			end := i
+			switch {
+			case c == '"' || c == '\'' || c == '`':
+				end = skipString(src, i)
+			case c == '/' && regexAllowed():
+				end = skipRegex(src, i)
+			}
+			line.WriteString(src[i : end+1])
+			i = end
+		}
+	}
+	flush()
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// skipRegex returns the index of the slash that closes the regular expression starting at src[start]. A slash inside
+// a character class does not end it.
+func skipRegex(src string, start int) int {
+	inClass := false
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				return i
+			}
+		case '\n':
+			return i - 1
+		}
+	}
+	return len(src) - 1
+}
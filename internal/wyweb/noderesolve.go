@@ -34,8 +34,10 @@
 package wyweb
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -47,44 +49,140 @@ import (
 )
 
 // includeExclude resolves which includables (styles or scripts) will be used on the page.
-// local - the new includables of the cueent node
-// include - the in
+// local - the includables defined by the current node
+// include - the includables asked for by the node or inherited from its parent
+// exclude - the includables left out of the node and its descendants
 func includeExclude(local []string, include []string, exclude []string) []string {
 	result := make([]string, 0)
-	for _, name := range local {
-		if slices.Contains(exclude, name) {
-			log.Printf("WARN: The locally defined %s, is already defined and set to be excluded. The new definition will be ignored.\n", name)
-			continue
-		} else if slices.Contains(include, name) {
-			log.Printf("WARN: The locally defined %s, is already defined. The new definition will be ignored.\n", name)
+	for _, key := range slices.Concat(local, include) {
+		if !slices.Contains(exclude, key) && !slices.Contains(result, key) {
+			result = append(result, key)
 		}
-		result = append(result, name)
 	}
-	for _, name := range include {
-		//exists := slices.Contains(local, name)
-		excluded := slices.Contains(exclude, name)
-		if !excluded {
-			result = append(result, name)
+	return result
+}
+
+// rootScope begins a resource name that refers to the definition in the root wyweb file, or to a built-in resource,
+// even where a nearer definition shadows it.
+const rootScope = "@root/"
+
+// resourceKey is the name the resource called name, defined in the wyweb file of the node at path, is registered
+// under. Resources of the root keep their own names.
+func resourceKey(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + ":" + name
+}
+
+// resourceName is the name a resource was given in its wyweb file.
+func resourceName(key string) string {
+	if _, name, scoped := strings.Cut(key, ":"); scoped {
+		return name
+	}
+	return key
+}
+
+// lookupResource finds the key of the resource that name refers to from this node: the nearest definition on the
+// way up to the root.
+func (node *ConfigNode) lookupResource(name string) string {
+	if global, ok := strings.CutPrefix(name, rootScope); ok {
+		return global
+	}
+	for n := node; n != nil && n != node.Tree.Root; n = n.Parent {
+		if _, ok := n.Resources[name]; ok {
+			return resourceKey(n.Path, name)
 		}
 	}
-	return result
+	return name
+}
+
+// lookupResources finds the keys of several resources with lookupResource.
+func (node *ConfigNode) lookupResources(names []string) []string {
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = node.lookupResource(name)
+	}
+	return out
 }
-func (tree *ConfigTree) resolveResourceDeps(res []string) []string {
-	names := make([]string, len(res))
-	copy(names, res)
-	keepGoing := true
-	for keepGoing {
-		keepGoing = false
-		for i := 0; i < len(names); i++ {
-			for _, dep := range tree.Resources[names[i]].DependsOn {
-				if !slices.Contains(names, dep) {
-					keepGoing = true
-					names = append(names, dep)
-				}
+
+// resolveResourceDeps orders resources so that each comes after everything it depends on, styles and scripts alike,
+// adding any dependencies that were not asked for. Otherwise, the resources keep the order they were given in.
+// Dependencies that do not exist and cycles are reported in the error. A cycle is broken where it closes, so that
+// every resource is still placed on the page.
+func (tree *ConfigTree) resolveResourceDeps(res []string) ([]string, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+	names := make([]string, 0, len(res))
+	stack := make([]string, 0)
+	errs := make([]error, 0)
+	var visit func(string)
+	visit = func(name string) {
+		switch state[name] {
+		case visited:
+			return
+		case visiting:
+			cycle := slices.Concat(stack[slices.Index(stack, name):], []string{name})
+			errs = append(errs, fmt.Errorf("dependency cycle %s", strings.Join(cycle, " -> ")))
+			return
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range tree.Resources[name].DependsOn {
+			if _, ok := tree.Resources[dep]; !ok {
+				errs = append(errs, fmt.Errorf("%s depends on %s, which does not exist", name, dep))
+				continue
 			}
+			visit(dep)
 		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		names = append(names, name)
+	}
+	for _, name := range res {
+		visit(name)
 	}
-	return names
+	return names, errors.Join(errs...)
+}
+
+// checkResourceDeps reports the missing dependencies and cycles among all of the site's resources. Pages are not
+// warned about one by one, since they would all repeat the same few problems.
+func (tree *ConfigTree) checkResourceDeps() {
+	tree.RLock()
+	names := make([]string, 0, len(tree.Resources))
+	for name := range tree.Resources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	_, err := tree.resolveResourceDeps(names)
+	tree.RUnlock()
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			log.Printf("ERROR: resources: %s", line)
+		}
+	}
+}
+
+// orderedResources gives the resources of the page, both those asked for and those whose conditions it meets, in the
+// order they are placed on it. The order is worked out once and kept until the resources of the page change.
+func (node *ConfigNode) orderedResources() []string {
+	node.resourceLock.Lock()
+	defer node.resourceLock.Unlock()
+	if node.resourceOrder == nil {
+		// Any problems are reported by checkResourceDeps, when the site is loaded and whenever it changes.
+		node.resourceOrder, _ = node.Tree.resolveResourceDeps(slices.Concat(node.LocalResources, node.automaticResources(), node.highlightResources()))
+	}
+	return node.resourceOrder
+}
+
+// forgetResourceOrder discards the order of the page's resources after they have changed.
+func (node *ConfigNode) forgetResourceOrder() {
+	node.resourceLock.Lock()
+	node.resourceOrder = nil
+	node.resourceLock.Unlock()
 }
 
 func regiserTag(tag string, item Listable, tagdb *map[string][]Listable) {
@@ -120,6 +218,7 @@ func (node *ConfigNode) resolveIncludes() {
 	}
 	node.LocalResources = make([]string, 0)
 	local := make([]string, 0)
+	defined := make(map[string]Resource, len(node.Resources))
 	for name, value := range node.Resources {
 		if value.Method == "url" || value.Method == "local" {
 			var err error
@@ -133,39 +232,76 @@ func (node *ConfigNode) resolveIncludes() {
 			value.Value = strings.TrimLeft(value.Value, string(os.PathSeparator))
 			node.Dependencies[value.Value] = KindResourceLocal
 		}
-		local = append(local, name)
-		_, ok := node.Tree.Resources[name]
-		if ok {
-			log.Printf("WARN: In configuration %s, the Resource %s is already defined. The new definition will be ignored.\n", node.Path, name)
-		} else {
-			node.Tree.Resources[name] = value
+		value.DependsOn = node.lookupResources(value.DependsOn)
+		key := resourceKey(node.Path, name)
+		local = append(local, key)
+		defined[key] = value
+	}
+	slices.Sort(local)
+	// Pages are being served while the node is rebuilt, so the new definitions replace the old all at once. Anything
+	// this node defined before but no longer does is forgotten.
+	node.Tree.Lock()
+	for key := range node.Tree.Resources {
+		if _, ok := defined[key]; !ok && strings.HasPrefix(key, resourceKey(node.Path, "")) {
+			delete(node.Tree.Resources, key)
 		}
 	}
-	includes := util.ConcatUnique(node.Include, node.Parent.LocalResources)
-	excludes := make([]string, len(node.Parent.Exclude))
-	copy(excludes, node.Parent.Exclude)
+	maps.Copy(node.Tree.Resources, defined)
+	node.Tree.Unlock()
+	// Resources defined here shadow those of the same name defined further up.
+	inherited := slices.DeleteFunc(slices.Clone(node.Parent.LocalResources), func(key string) bool {
+		_, shadowed := node.Resources[resourceName(key)]
+		return shadowed
+	})
+	include := node.lookupResources(node.Include)
+	includes := util.ConcatUnique(include, inherited)
+	excludes := node.Parent.lookupResources(node.Parent.Exclude)
 	// any excludes of the parent are overridden by local includes. Otherwise, they are inherited.
 	n := 0
 	for _, x := range excludes {
-		if !slices.Contains(node.Include, x) {
+		if !slices.Contains(include, x) {
 			excludes[n] = x
 			n++
 		}
 	}
-	excludes = util.ConcatUnique(excludes[:n], node.Exclude)
-	node.LocalResources = node.Tree.resolveResourceDeps(includeExclude(local, includes, excludes))
+	excludes = util.ConcatUnique(excludes[:n], node.lookupResources(node.Exclude))
+	node.LocalResources = includeExclude(local, includes, excludes)
+	node.excluded = excludes
+	node.forgetResourceOrder()
 }
 
 func (node *ConfigNode) inheritIfUndefined() {
 	if node.Updated.IsZero() {
 		node.Updated = node.Date
 	}
+	if !node.Footer.IsZero() && node.Footer.dir == "" {
+		node.Footer.dir = "/" + node.Path
+	}
+	if node.Parent == nil {
+		return
+	}
 	if node.Author == "" {
 		node.Author = node.Parent.Author
 	}
 	if node.Copyright == "" {
 		node.Copyright = node.Parent.Copyright
 	}
+	if node.Related.IsZero() {
+		node.Related = node.Parent.Related
+	}
+	if node.Feed.IsZero() {
+		node.Feed = node.Parent.Feed
+	}
+	if node.Footer.IsZero() {
+		node.Footer = node.Parent.Footer
+	}
+	node.Robots.inherit(node.Parent.Robots)
+	node.Highlight.inherit(node.Parent.Highlight)
+	// Anything beneath a protected page is protected as well.
+	node.Protected = node.Protected || node.Parent.Protected
+	if node.Draft || node.Protected {
+		node.Robots.hide()
+	}
 }
 
 // copy fields from src to dst only if the corresponding field of dst is zero/empty.
@@ -179,6 +315,9 @@ func mergePageData(dst *PageData, src *PageData) {
 	if dst.Description == "" {
 		dst.Description = src.Description
 	}
+	if dst.Image == "" {
+		dst.Image = src.Image
+	}
 	if dst.Copyright == "" {
 		dst.Copyright = src.Copyright
 	}
@@ -203,6 +342,29 @@ func mergePageData(dst *PageData, src *PageData) {
 	if dst.Up.IsZero() {
 		dst.Up = src.Up
 	}
+	if dst.Related.IsZero() {
+		dst.Related = src.Related
+	}
+	if dst.Feed.IsZero() {
+		dst.Feed = src.Feed
+	}
+	if dst.Footer.IsZero() {
+		dst.Footer = src.Footer
+	}
+	dst.Draft = dst.Draft || src.Draft
+	dst.Protected = dst.Protected || src.Protected
+	if dst.Robots.IsZero() {
+		dst.Robots = src.Robots
+	}
+	if dst.Highlight.IsZero() {
+		dst.Highlight = src.Highlight
+	}
+	if dst.Layout == "" {
+		dst.Layout = src.Layout
+	}
+	if dst.Translations == nil {
+		dst.Translations = src.Translations
+	}
 }
 
 func (node *ConfigNode) SetFieldsFromWyWebMeta(meta *WyWebMeta) error {
@@ -212,12 +374,12 @@ func (node *ConfigNode) SetFieldsFromWyWebMeta(meta *WyWebMeta) error {
 		temp := (*meta).(*WyWebRoot)
 		node.PageData = *temp.GetPageData()
 		copyHeadData(&node.HeadData, temp.GetHeadData())
-		node.LocalResources = make([]string, len(temp.Default.Resources))
-		copy(node.LocalResources, temp.Default.Resources)
+		node.excluded = node.lookupResources(temp.Exclude)
+		node.LocalResources = defaultRootResources(node.lookupResources(temp.Default.Resources), node.excluded)
 		node.resolved = true
 		node.Path = ""
 		return nil
-	case *WyWebPost, *WyWebListing, *WyWebGallery:
+	case *WyWebPost, *WyWebListing, *WyWebGallery, *WyWebPodcast, *WyWebEpisode, *WyWebRecipe, *WyWebPage:
 		if !node.Parent.resolved {
 			node.Parent.resolve()
 		}
@@ -255,6 +417,46 @@ func (node *ConfigNode) SetFieldsFromWyWebMeta(meta *WyWebMeta) error {
 				node.Preview = t.Preview
 			}
 		}
+		if node.Series.IsZero() {
+			node.Series = t.Series
+		}
+	case *WyWebEpisode:
+		node.Tags = util.ConcatUnique(node.Tags, t.Tags)
+		if node.Episode != nil {
+			node.Episode.WWEpisode = t.WWEpisode
+		}
+	case *WyWebRecipe:
+		// Unlike a post, a recipe need not have any markdown, since the recipe itself is in the wyweb file.
+		if node.Index == "" && t.Index != "" {
+			node.Index = filepath.Join(node.Path, t.Index)
+			if _, err := os.Stat(node.Index); err != nil {
+				log.Printf("WARN: Could not find index for %s specified at %s", node.Path, t.Index)
+				return fmt.Errorf("could not find index for %s specified at %s", node.Path, t.Index)
+			}
+		}
+		node.Tags = util.ConcatUnique(node.Tags, t.Tags)
+		if node.Preview == "" {
+			node.Preview = t.Preview
+		}
+		if node.Series.IsZero() {
+			node.Series = t.Series
+		}
+		recipe := t.WWRecipe
+		node.Recipe = &recipe
+	case *WyWebPage:
+		if node.Index == "" && t.Index != "" {
+			node.Index = filepath.Join(node.Path, t.Index)
+		} else if node.Index == "" {
+			index, err := findIndexPath(node.Path)
+			if err != nil {
+				log.Printf("WARN: Could not find index for %s", node.Path)
+				return fmt.Errorf("could not find index for %s", node.Path)
+			}
+			node.Index = index
+		}
+		node.Page = t.WWPage
+	case *WyWebPodcast:
+		node.Podcast = t.WWPodcast
 	case *WyWebGallery:
 		node.Images = make([]RichImage, len(t.GalleryItems))
 		copy(node.Images, t.GalleryItems)
@@ -266,9 +468,12 @@ func (node *ConfigNode) SetFieldsFromWyWebMeta(meta *WyWebMeta) error {
 }
 
 func (node *ConfigNode) registerTags() {
+	if node.Draft {
+		return
+	}
 	tree := node.Tree
 	switch node.NodeKind {
-	case WWPOST:
+	case WWPOST, WWRECIPE:
 		for _, tag := range node.Tags {
 			regiserTag(tag, node, &node.Tree.TagDB)
 			if node.Parent != tree.Root {
@@ -308,7 +513,7 @@ func (node *ConfigNode) setAbsoluteIndex() error {
 
 func (node *ConfigNode) Magic() {
 	switch node.NodeKind {
-	case WWPOST:
+	case WWPOST, WWRECIPE, WWPAGE:
 		mdfile, err := os.ReadFile(node.Index)
 		if node.Title == "" {
 			if err == nil {
@@ -320,9 +525,15 @@ func (node *ConfigNode) Magic() {
 				GetPreviewFromMarkdown(node, mdfile, nil)
 			}
 		}
+		if node.Preview == "" && node.Recipe != nil {
+			node.Preview = node.Recipe.summary()
+		}
 		if node.Description == "" {
 			node.Description = node.Preview
 		}
+		if node.Episode != nil {
+			node.Episode.readAudioInfo()
+		}
 	}
 }
 
@@ -338,12 +549,14 @@ func (node *ConfigNode) resolve() error {
 			return err
 		}
 	}
-	err := node.setAbsoluteIndex()
-	if err != nil {
-		log.Println(err.Error())
-		return err
+	if node.Index != "" || node.Recipe == nil {
+		err := node.setAbsoluteIndex()
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+		node.Dependencies[node.Index] = KindMDSource
 	}
-	node.Dependencies[node.Index] = KindMDSource
 	node.Magic()
 	if node.StructuredData == nil {
 		node.StructuredData = make([]string, 0)
@@ -353,8 +566,10 @@ func (node *ConfigNode) resolve() error {
 	}
 	node.resolveIncludes()
 	node.inheritIfUndefined()
+	node.Tree.registerHighlightStyles(node.Highlight)
 	node.SetID()
 	node.registerTags()
+	node.registerSeries()
 	node.LastRead = time.Now()
 	node.resolved = true
 	//fmt.Printf("%s\n\t", node.Title)
@@ -378,6 +593,11 @@ func setNavLink(nl *WWNavLink, path, title string) {
 }
 
 func rerenderNavLinks(node *ConfigNode) {
+	node.forgetVariants()
+	if node.HTML != nil && node.Tree.Theme.Has("post", node.Layout) {
+		node.invalidate()
+		return
+	}
 	oldnav, err := node.HTML.FirstElementByClass("navlinks")
 	if err != nil {
 		return
@@ -389,11 +609,13 @@ func rerenderNavLinks(node *ConfigNode) {
 func setNavLinksOfChildren(node *ConfigNode) {
 	//node.Lock()
 	//defer node.Unlock()
-	siblings := make([]*ConfigNode, len(node.Children))
-	i := 0
+	siblings := make([]*ConfigNode, 0, len(node.Children))
 	for _, child := range node.Children {
-		siblings[i] = child
-		i++
+		// Plain pages stand apart from the posts around them.
+		if child.NodeKind == WWPAGE {
+			continue
+		}
+		siblings = append(siblings, child)
 	}
 	sort.Slice(siblings, func(i, j int) bool {
 		return siblings[i].Date.Before(siblings[j].Date)
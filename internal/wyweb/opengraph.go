@@ -0,0 +1,224 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// A <meta> tag describing the page to social media sites. OpenGraph tags are keyed by property, and Twitter tags by
+// name.
+type SocialMeta struct {
+	Property string
+	Name     string
+	Content  string
+}
+
+type socialImage struct {
+	URL string
+	Alt string
+}
+
+func ogTag(property string, content string) SocialMeta {
+	return SocialMeta{Property: property, Content: content}
+}
+
+func twitterTag(name string, content string) SocialMeta {
+	return SocialMeta{Name: name, Content: content}
+}
+
+// resolveImageURL makes the absolute URL of an image given in a wyweb file, which may be an absolute URL, a path from
+// the document root, or a path relative to the page.
+func resolveImageURL(node *ConfigNode, image string) string {
+	baseURL := "https://" + node.Tree.Domain + "/"
+	if strings.Contains(image, "://") || strings.HasPrefix(image, "/") {
+		return absoluteURL(image, baseURL)
+	}
+	return baseURL + filepath.Join(node.fileDir(), image)
+}
+
+// fileDir gives the directory holding the files of a page. Most pages are directories of their own, but pages made
+// from a single file, such as magic posts and podcast episodes, live in the directory of their parent.
+func (node *ConfigNode) fileDir() string {
+	if st, err := os.Stat(node.RealPath); err == nil && !st.IsDir() {
+		return filepath.Dir(node.RealPath)
+	}
+	return node.RealPath
+}
+
+// firstPostImage finds the first image in the body of a post.
+func (node *ConfigNode) firstPostImage() socialImage {
+	var out socialImage
+	doc, source, err := node.parsedDocument()
+	if err != nil {
+		return out
+	}
+	baseURL := "https://" + node.Tree.Domain + "/"
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if img, ok := n.(*ast.Image); ok {
+			if loc := absoluteURL(string(img.Destination), baseURL); loc != "" {
+				out = socialImage{URL: loc, Alt: string(img.Text(source))}
+				return ast.WalkStop, nil
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return out
+}
+
+// socialImage picks the image shown alongside a shared link. An image given explicitly in the wyweb file wins,
+// followed by the first image of a post or gallery. Failing that, the nearest ancestor with an explicit image is used.
+func (node *ConfigNode) socialImage() socialImage {
+	if node.Image != "" {
+		return socialImage{URL: resolveImageURL(node, node.Image)}
+	}
+	switch node.NodeKind {
+	case WWPOST, WWRECIPE:
+		if img := node.firstPostImage(); img.URL != "" {
+			return img
+		}
+	case WWGALLERY:
+		if len(node.Images) > 0 {
+			return node.Images[0].socialImage()
+		}
+	}
+	for ancestor := node.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor.Image != "" {
+			return socialImage{URL: resolveImageURL(ancestor, ancestor.Image)}
+		}
+	}
+	return socialImage{}
+}
+
+func (r *RichImage) socialImage() socialImage {
+	imageURL := url.URL{
+		Scheme: "https",
+		Host:   r.ParentPage.Tree.Domain,
+		Path:   "/" + filepath.Join(r.ParentPage.Path, r.Filename),
+	}
+	return socialImage{URL: imageURL.String(), Alt: r.Alt}
+}
+
+func appendImageTags(tags []SocialMeta, img socialImage) []SocialMeta {
+	if img.URL == "" {
+		return append(tags, twitterTag("twitter:card", "summary"))
+	}
+	tags = append(tags, ogTag("og:image", img.URL))
+	if img.Alt != "" {
+		tags = append(tags, ogTag("og:image:alt", img.Alt))
+	}
+	tags = append(tags, twitterTag("twitter:card", "summary_large_image"), twitterTag("twitter:image", img.URL))
+	if img.Alt != "" {
+		tags = append(tags, twitterTag("twitter:image:alt", img.Alt))
+	}
+	return tags
+}
+
+// SocialMeta generates the OpenGraph and Twitter card tags for a page.
+func (node *ConfigNode) SocialMeta() []SocialMeta {
+	pageURL := node.pageURL()
+	ogType := "website"
+	if node.isArticle() {
+		ogType = "article"
+	}
+	tags := []SocialMeta{
+		ogTag("og:type", ogType),
+		ogTag("og:url", pageURL),
+		ogTag("og:title", node.Title),
+	}
+	if node.Tree.Root != nil && node.Tree.Root.Title != "" {
+		tags = append(tags, ogTag("og:site_name", node.Tree.Root.Title))
+	}
+	tags = append(tags, twitterTag("twitter:title", node.Title))
+	if node.Description != "" {
+		tags = append(tags,
+			ogTag("og:description", node.Description),
+			twitterTag("twitter:description", node.Description),
+		)
+	}
+	tags = appendImageTags(tags, node.socialImage())
+	if !node.isArticle() {
+		return tags
+	}
+	if !node.Date.IsZero() {
+		tags = append(tags, ogTag("article:published_time", node.Date.Format(time.RFC3339)))
+	}
+	if !node.Updated.IsZero() && !node.Updated.Equal(node.Date) {
+		tags = append(tags, ogTag("article:modified_time", node.Updated.Format(time.RFC3339)))
+	}
+	if node.Author != "" {
+		tags = append(tags, ogTag("article:author", node.Author))
+	}
+	for _, tag := range node.Tags {
+		tags = append(tags, ogTag("article:tag", tag))
+	}
+	return tags
+}
+
+// SocialMeta generates the tags for a single gallery image, so that a link to the image shows the image itself rather
+// than the gallery as a whole.
+func (r *RichImage) SocialMeta() []SocialMeta {
+	page := r.ParentPage
+	pageURL := "https://" + page.Tree.Domain + "/" + page.Path + "?image=" + url.QueryEscape(r.GetIDb64())
+	title := r.Title
+	if title == "" {
+		title = page.Title
+	}
+	description := r.Description
+	if description == "" {
+		description = page.Description
+	}
+	tags := []SocialMeta{
+		ogTag("og:type", "website"),
+		ogTag("og:url", pageURL),
+		ogTag("og:title", title),
+	}
+	if page.Tree.Root != nil && page.Tree.Root.Title != "" {
+		tags = append(tags, ogTag("og:site_name", page.Tree.Root.Title))
+	}
+	tags = append(tags, twitterTag("twitter:title", title))
+	if description != "" {
+		tags = append(tags, ogTag("og:description", description), twitterTag("twitter:description", description))
+	}
+	return appendImageTags(tags, r.socialImage())
+}
@@ -0,0 +1,134 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wyweb.site/util"
+)
+
+// MagicPage creates a page from a single markdown file, whose front matter holds its settings.
+func MagicPage(parent *ConfigNode, name string) *ConfigNode {
+	out := &ConfigNode{
+		Parent:       parent,
+		NodeKind:     WWPAGE,
+		Children:     make(map[string]*ConfigNode),
+		TagDB:        make(map[string][]Listable),
+		Dependencies: make(map[string]DependencyKind),
+		Tree:         parent.Tree,
+		Index:        filepath.Join(parent.RealPath, name),
+		RealPath:     filepath.Join(parent.RealPath, name),
+	}
+	out.Path = filepath.Join(util.TrimMagicSuffix(parent.Path), strings.TrimSuffix(name, ".page.md"))
+	meta, err := ReadWyWeb(filepath.Join(parent.RealPath, name), "!page")
+	if err == nil {
+		meta.(*WyWebPage).Path = out.Path
+		out.Data = &meta
+	}
+	return out
+}
+
+func shown(part *bool) bool {
+	return part == nil || *part
+}
+
+func (p WWPage) ShowBreadcrumbs() bool {
+	return shown(p.Breadcrumbs)
+}
+
+func (p WWPage) ShowHeading() bool {
+	return shown(p.Heading)
+}
+
+func (p WWPage) ShowTOC() bool {
+	return shown(p.TOC)
+}
+
+func (p WWPage) ShowMenu() bool {
+	return shown(p.Menu)
+}
+
+// BuildPage renders a plain page, such as an "About" or "Contact" page. Unlike a post, it has no publication date,
+// author, navigation links, or tags; only the breadcrumbs and title are added to what the user wrote, and either may
+// be turned off.
+func BuildPage(node *ConfigNode) error {
+	mdtext, err := os.ReadFile(node.Index)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+	temp, TOC, title, _ := MDConvertPost(mdtext, node)
+	if !node.Page.ShowTOC() {
+		TOC = nil
+	}
+	var crumbs *HTMLElement
+	if node.Page.ShowBreadcrumbs() {
+		var bcSD string
+		crumbs, bcSD = Breadcrumbs(node)
+		node.StructuredData = append(node.StructuredData, bcSD)
+	}
+	if !node.Page.ShowHeading() {
+		title = nil
+	}
+	if themed, ok := node.themedPost("page", temp.String(), TOC, crumbs); ok {
+		node.HTML = themed
+	} else {
+		node.HTML = buildPageBody(temp.String(), TOC, title, crumbs)
+	}
+	node.StructuredData = append(node.StructuredData, node.pageStructuredData())
+	return nil
+}
+
+func buildPageBody(content string, TOC, title, crumbs *HTMLElement) *HTMLElement {
+	body := NewHTMLElement("body")
+	if TOC != nil {
+		body.Append(TOC)
+	}
+	article := body.AppendNew("article", Class("page"))
+	if crumbs != nil || title != nil {
+		header := article.AppendNew("header")
+		if crumbs != nil {
+			header.Append(crumbs)
+		}
+		if title != nil {
+			header.Append(title)
+		}
+	}
+	article.AppendText(content).NoIndent()
+	return body
+}
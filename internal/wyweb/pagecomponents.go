@@ -37,8 +37,9 @@ import (
 	"bytes"
 	_ "embed"
 	"encoding/json"
-	"fmt"
+	"log"
 	"net/url"
+	"path"
 	"slices"
 	"strings"
 	"time"
@@ -49,30 +50,55 @@ import (
 //go:embed logo.svg
 var logoString string
 
+func appendStyle(parent *HTMLElement, style interface{}) {
+	switch s := style.(type) {
+	case URLResource:
+		parent.AppendNew("link", map[string]string{"rel": "stylesheet", "href": s.String}, s.Attributes)
+	case RawResource:
+		tag := parent.AppendNew("style", s.Attributes)
+		tag.AppendText(s.String)
+	}
+}
+
+func appendScript(parent *HTMLElement, script interface{}) {
+	switch s := script.(type) {
+	case URLResource:
+		parent.AppendNew("script", map[string]string{"src": s.String}, s.Attributes)
+	case RawResource:
+		tag := parent.AppendNew("script", s.Attributes)
+		tag.AppendText(s.String)
+	}
+}
+
 func BuildHead(headData HTMLHeadData) *HTMLElement {
 	head := NewHTMLElement("head")
 	title := head.AppendNew("title")
 	title.AppendText(headData.Title)
+	for _, alt := range headData.Alternates {
+		head.AppendNew("link", map[string]string{"rel": "alternate", "hreflang": alt.Lang, "href": alt.URL})
+	}
+	for _, hint := range headData.Hints {
+		head.AppendNew("link", hint)
+	}
 	for _, style := range headData.Styles {
-		switch s := style.(type) {
-		case URLResource:
-			head.AppendNew("link", map[string]string{"rel": "stylesheet", "href": s.String}, s.Attributes)
-		case RawResource:
-			tag := head.AppendNew("style", s.Attributes)
-			tag.AppendText(s.String)
-		default:
-			continue
-		}
+		appendStyle(head, style)
 	}
 	for _, script := range headData.Scripts {
-		switch s := script.(type) {
-		case URLResource:
-			head.AppendNew("script", map[string]string{"src": s.String, "async": ""}, s.Attributes)
-		case RawResource:
-			tag := head.AppendNew("script", s.Attributes)
-			tag.AppendText(s.String)
-		default:
-			continue
+		appendScript(head, script)
+	}
+	robots := make([]string, 0, len(headData.Robots))
+	for name := range headData.Robots {
+		robots = append(robots, name)
+	}
+	slices.Sort(robots)
+	for _, name := range robots {
+		head.AppendNew("meta", map[string]string{"name": name, "content": headData.Robots[name]})
+	}
+	for _, tag := range headData.Social {
+		if tag.Property != "" {
+			head.AppendNew("meta", map[string]string{"property": tag.Property, "content": tag.Content})
+		} else {
+			head.AppendNew("meta", map[string]string{"name": tag.Name, "content": tag.Content})
 		}
 	}
 	head.AppendText(strings.Join(headData.Meta, "\n"))
@@ -80,35 +106,151 @@ func BuildHead(headData HTMLHeadData) *HTMLElement {
 }
 
 func (node *ConfigNode) BuildDocument() (bytes.Buffer, error) {
-	return BuildDocument(node.HTML, *node.GetHTMLHeadData(), node.StructuredData...)
+	return node.RenderDocument(node.HTML, *node.GetHTMLHeadData(), node.DocumentStructuredData()...)
+}
+
+func buildDocumentHead(headData HTMLHeadData, structuredData ...string) *HTMLElement {
+	head := BuildHead(headData)
+	for _, data := range structuredData {
+		head.AppendNew("script", map[string]string{"type": "application/ld+json"}).AppendText(data)
+	}
+	return head
+}
+
+// withBodyResources puts the resources placed in the body at its end without altering the body itself, which is cached.
+func withBodyResources(body *HTMLElement, headData HTMLHeadData) *HTMLElement {
+	if body == nil || len(headData.BodyStyles)+len(headData.BodyScripts) == 0 {
+		return body
+	}
+	out := *body
+	out.Children = slices.Clone(body.Children)
+	for _, style := range headData.BodyStyles {
+		appendStyle(&out, style)
+	}
+	for _, script := range headData.BodyScripts {
+		appendScript(&out, script)
+	}
+	return &out
 }
 
 func BuildDocument(bodyHTML *HTMLElement, headData HTMLHeadData, structuredData ...string) (bytes.Buffer, error) {
 	var buf bytes.Buffer
 	buf.WriteString("<!DOCTYPE html>\n")
 	document := NewHTMLElement("html")
-	head := BuildHead(headData)
-	for _, data := range structuredData {
-		head.AppendNew("script", map[string]string{"type": "application/ld+json"}).AppendText(data)
+	if headData.Lang != "" {
+		document.Attributes["lang"] = headData.Lang
 	}
-	document.Append(head)
-	document.Append(bodyHTML)
+	document.Append(buildDocumentHead(headData, structuredData...))
+	document.Append(withBodyResources(bodyHTML, headData))
 	RenderHTML(document, &buf)
 	return buf, nil
 }
+
+// BuildFooter renders the footer of a page as given by its footer setting, or nil if the footer has been turned off.
 func BuildFooter(node *ConfigNode) *HTMLElement {
+	if node.Footer.Hidden {
+		return nil
+	}
+	if footer, ok := node.themedFooter(); ok {
+		return footer
+	}
+	settings := node.Footer
 	footer := NewHTMLElement("footer")
-	logoContainer := footer.AppendNew("div", Class("wyweb-logo"))
-	logoContainer.AppendNew("span").AppendText("Powered by")
-	logoContainer.AppendText(logoString)
-	logoContainer.AppendNew("a", Href("https://wyweb.site"))
+	if settings.Content != "" {
+		footer.Append(node.footerContent())
+	}
+	if len(settings.Columns) > 0 {
+		columns := footer.AppendNew("div", Class("footer-columns"))
+		for _, column := range settings.Columns {
+			col := columns.AppendNew("div", Class("footer-column"))
+			if column.Title != "" {
+				col.AppendNew("h2", Class("footer-column-title")).AppendText(column.Title)
+			}
+			list := col.AppendNew("ul")
+			for _, link := range column.Links {
+				list.AppendNew("li").AppendNew("a", Href(settings.resolve(link.Path))).AppendText(link.Text)
+			}
+		}
+	}
+	if len(settings.Badges) > 0 {
+		badges := footer.AppendNew("div", Class("footer-badges"))
+		for _, badge := range settings.Badges {
+			parent := badges
+			if badge.Link != "" {
+				parent = badges.AppendNew("a", Href(settings.resolve(badge.Link)))
+			}
+			parent.AppendNew("img", Class("footer-badge"), map[string]string{
+				"src": settings.resolve(badge.Image),
+				"alt": badge.Alt,
+			})
+		}
+	}
+	if !settings.HideLogo {
+		logoContainer := footer.AppendNew("div", Class("wyweb-logo"))
+		logoContainer.AppendNew("span").AppendText(node.T("Powered by"))
+		logoContainer.AppendText(logoString)
+		logoContainer.AppendNew("a", Href("https://wyweb.site"))
+	}
 	copyrightMsg := footer.AppendNew("span", Class("copyright"))
-	copyrightMsg.AppendText(
-		fmt.Sprintf("Copyright © %d %s", time.Now().Year(), node.Copyright),
-	)
+	year := time.Now().Year()
+	if first := node.Tree.copyrightSince(settings); first != 0 && first < year {
+		copyrightMsg.AppendText(node.T("Copyright © %d–%d %s", first, year, node.Copyright))
+	} else {
+		copyrightMsg.AppendText(node.T("Copyright © %d %s", year, node.Copyright))
+	}
 	return footer
 }
 
+// footerContent renders the markdown of the footer the same way as a post, with links relative to the page the
+// footer was given on.
+func (node *ConfigNode) footerContent() *HTMLElement {
+	sourceEmbeds := make([]string, 0)
+	md := newMarkdown(strings.TrimPrefix(node.Footer.dir, "/"), &sourceEmbeds)
+	text := []byte(node.Footer.Content)
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, text, ParsePost(md, text, node.Footer.dir)); err != nil {
+		log.Printf("WARN: could not render the footer of %s: %s", node.Path, err.Error())
+	}
+	for _, s := range sourceEmbeds {
+		node.Dependencies[s] = KindFileEmbed
+	}
+	content := NewHTMLElement("div", Class("footer-content"))
+	content.AppendText(strings.TrimRight(buf.String(), "\n"))
+	return content
+}
+
+// resolve makes a link or image path of the footer relative to the document root, so that it works on every page the
+// footer is inherited by. URLs and paths that are already absolute are left alone.
+func (f WWFooter) resolve(dest string) string {
+	if dest == "" || dest[0] == '/' || dest[0] == '#' || strings.Contains(dest, ":") {
+		return dest
+	}
+	return path.Join("/", f.dir, dest)
+}
+
+// copyrightSince is the first year of the copyright notice if it should be a range: either the year the footer gives,
+// or the year the first page of the site was published.
+func (tree *ConfigTree) copyrightSince(settings WWFooter) int {
+	if settings.Since != 0 {
+		return settings.Since
+	}
+	if !settings.YearRange {
+		return 0
+	}
+	var first time.Time
+	var dft func(*ConfigNode)
+	dft = func(node *ConfigNode) {
+		if !node.Draft && !node.Date.IsZero() && (first.IsZero() || node.Date.Before(first)) {
+			first = node.Date
+		}
+		for _, child := range node.Children {
+			dft(child)
+		}
+	}
+	dft(tree.Root)
+	return first.Year()
+}
+
 func Breadcrumbs(node *ConfigNode, extraCrumbs ...WWNavLink) (*HTMLElement, string) {
 	structuredData := map[string]interface{}{
 		"@context": "https://schema.org",
@@ -126,7 +268,7 @@ func Breadcrumbs(node *ConfigNode, extraCrumbs ...WWNavLink) (*HTMLElement, stri
 		for temp != nil && idx >= 0 {
 			crumbs[idx] = WWNavLink{
 				Path: "/" + temp.Path,
-				Text: temp.Title,
+				Text: temp.titleIn(node.Lang),
 			}
 			idx--
 			temp = temp.Parent
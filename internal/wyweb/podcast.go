@@ -0,0 +1,494 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"wyweb.site/util"
+
+	wwExt "wyweb.site/extensions"
+)
+
+// The audio of a podcast episode along with its settings.
+type PodcastEpisode struct {
+	WWEpisode
+	Audio    string // Path of the audio file from the document root
+	MIMEType string
+	Size     int64
+	Length   time.Duration
+}
+
+// Namespace from which the GUIDs of podcasts are derived, as defined by the Podcasting 2.0 namespace.
+var podcastGUIDNamespace = [16]byte{
+	0xea, 0xd4, 0xc2, 0x36, 0xbf, 0x58, 0x58, 0xc6, 0xa2, 0xc6, 0xa6, 0xb2, 0x8d, 0x12, 0x8c, 0xb6,
+}
+
+func isEpisodeAudio(name string) bool {
+	mimeType, ok := wwExt.MediaMIMEType(strings.TrimPrefix(filepath.Ext(name), "."))
+	return ok && strings.HasPrefix(mimeType, "audio/")
+}
+
+// childKey gives the name under which the page made from the file at path is found among the children of node.
+func (node *ConfigNode) childKey(path string) string {
+	if node.NodeKind == WWPODCAST && isEpisodeAudio(path) {
+		return strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return util.TrimMagicSuffix(path)
+}
+
+// MagicEpisode creates an episode of a podcast from its audio file. The show notes are the markdown file of the same
+// name, whose front matter holds the settings of the episode.
+func MagicEpisode(parent *ConfigNode, name string) (*ConfigNode, error) {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	audio := filepath.Join(parent.RealPath, name)
+	notes := filepath.Join(parent.RealPath, base+".md")
+	if _, err := os.Stat(notes); err != nil {
+		return nil, fmt.Errorf("%s has no show notes (expected %s)", audio, notes)
+	}
+	out := &ConfigNode{
+		Parent:       parent,
+		NodeKind:     WWPOST,
+		Children:     make(map[string]*ConfigNode),
+		TagDB:        make(map[string][]Listable),
+		Dependencies: make(map[string]DependencyKind),
+		Tree:         parent.Tree,
+		Index:        notes,
+		RealPath:     audio,
+		Episode:      &PodcastEpisode{Audio: audio},
+	}
+	out.Path = filepath.Join(util.TrimMagicSuffix(parent.Path), base)
+	// The episode goes away along with its audio, just as a post goes away along with its markdown.
+	out.Dependencies[audio] = KindMDSource
+	meta, err := ReadWyWeb(notes, "!episode")
+	if err == nil {
+		meta.(*WyWebEpisode).Path = out.Path
+		out.Data = &meta
+	}
+	return out, nil
+}
+
+// readAudioInfo fills in the details of the audio file. A duration given in the show notes takes precedence over the
+// one read from the file.
+func (e *PodcastEpisode) readAudioInfo() {
+	e.MIMEType, _ = wwExt.MediaMIMEType(strings.TrimPrefix(filepath.Ext(e.Audio), "."))
+	if st, err := os.Stat(e.Audio); err == nil {
+		e.Size = st.Size()
+	}
+	if e.Duration != "" {
+		length, err := parseTimestamp(e.Duration)
+		if err == nil {
+			e.Length = length
+			return
+		}
+		log.Printf("WARN: invalid duration %q for %s\n", e.Duration, e.Audio)
+	}
+	length, err := audioDuration(e.Audio)
+	if err != nil {
+		log.Printf("WARN: could not determine the duration of %s: %s\n", e.Audio, err.Error())
+	}
+	e.Length = length
+}
+
+// parseTimestamp reads a timestamp of the form [[hh:]mm:]ss[.fff].
+func parseTimestamp(stamp string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(stamp), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %s", stamp)
+	}
+	var total float64
+	for _, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid timestamp %s", stamp)
+		}
+		total = total*60 + value
+	}
+	return time.Duration(total * float64(time.Second)), nil
+}
+
+// formatTimestamp writes a duration as h:mm:ss, or m:ss if it is shorter than an hour.
+func formatTimestamp(d time.Duration) string {
+	total := int(d.Round(time.Second) / time.Second)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// normalPlayTime writes a duration as hh:mm:ss.fff, as used by Podlove Simple Chapters.
+func normalPlayTime(d time.Duration) string {
+	ms := int(d / time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func isoDuration(d time.Duration) string {
+	total := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("PT%dH%dM%dS", total/3600, total/60%60, total%60)
+}
+
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f kB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+func (e *PodcastEpisode) label(locale *Locale) string {
+	var number string
+	switch {
+	case e.Season > 0 && e.Number > 0:
+		number = locale.T("Season %d, Episode %d", e.Season, e.Number)
+	case e.Number > 0:
+		number = locale.T("Episode %d", e.Number)
+	case e.Season > 0:
+		number = locale.T("Season %d", e.Season)
+	}
+	switch strings.ToLower(e.Type) {
+	case "trailer", "bonus":
+		kind := locale.T(strings.ToUpper(e.Type[:1]) + strings.ToLower(e.Type[1:]))
+		if number == "" {
+			return kind
+		}
+		return kind + " · " + number
+	}
+	return number
+}
+
+// chapterStart gives the start of a chapter, logging any chapter whose start cannot be read.
+func (node *ConfigNode) chapterStart(chapter WWChapter) (time.Duration, bool) {
+	start, err := parseTimestamp(chapter.Start)
+	if err != nil {
+		log.Printf("WARN: chapter %q of %s: %s\n", chapter.Title, node.Path, err.Error())
+		return 0, false
+	}
+	return start, true
+}
+
+// BuildEpisodePlayer renders the audio player of an episode together with its details and chapters. The player itself
+// is the same one that embedding the audio in markdown would produce.
+func BuildEpisodePlayer(node *ConfigNode) *HTMLElement {
+	episode := node.Episode
+	player := NewHTMLElement("section", Class("episode-player"))
+	info := player.AppendNew("div", Class("episode-info"))
+	if label := episode.label(node.Tree.Locale(node.Language())); label != "" {
+		info.AppendNew("span", Class("episode-number")).AppendText(label)
+	}
+	if episode.Length > 0 {
+		info.AppendNew("time",
+			Class("episode-duration"),
+			map[string]string{"datetime": isoDuration(episode.Length)},
+		).AppendText(formatTimestamp(episode.Length))
+	}
+	src := "/" + episode.Audio
+	if media, ok := wwExt.NewMediaNode(src); ok {
+		var buf bytes.Buffer
+		err := newMarkdown(node.Path, nil).Renderer().Render(&buf, nil, media)
+		if err != nil {
+			log.Println(err.Error())
+		} else {
+			player.AppendText(buf.String()).NoIndent()
+		}
+	}
+	download := player.AppendNew("a", Class("episode-download"), Href(src), map[string]string{"download": ""})
+	download.AppendText(node.T("Download (%s)", formatFileSize(episode.Size)))
+	if len(episode.Chapters) == 0 {
+		return player
+	}
+	chapters := player.AppendNew("ol", Class("episode-chapters"))
+	for _, chapter := range episode.Chapters {
+		start, ok := node.chapterStart(chapter)
+		if !ok {
+			continue
+		}
+		item := chapters.AppendNew("li", map[string]string{"data-start": strconv.FormatFloat(start.Seconds(), 'f', -1, 64)})
+		item.AppendNew("time", map[string]string{"datetime": isoDuration(start)}).AppendText(formatTimestamp(start))
+		if chapter.URL != "" {
+			item.AppendNew("a", Href(chapter.URL)).AppendText(html.EscapeString(chapter.Title))
+		} else {
+			item.AppendNew("span").AppendText(html.EscapeString(chapter.Title))
+		}
+	}
+	return player
+}
+
+// BuildPodcast renders the page of a podcast, which lists its episodes like any other listing along with a link to
+// subscribe.
+func BuildPodcast(node *ConfigNode) error {
+	err := BuildDirListing(node)
+	if err != nil {
+		return err
+	}
+	header, err := node.HTML.FirstElementByClass("listing-header")
+	if err != nil {
+		// A listing rendered from a theme is left to link to podcast.xml itself.
+		return nil
+	}
+	header.AppendNew("a",
+		Class("podcast-subscribe"),
+		Href("/"+filepath.Join(node.RealPath, "podcast.xml")),
+		map[string]string{"type": "application/rss+xml"},
+	).AppendText(node.T("Subscribe"))
+	return nil
+}
+
+// ChaptersJSON gives the chapters of an episode in the JSON chapters format of the Podcasting 2.0 namespace.
+func (node *ConfigNode) ChaptersJSON() ([]byte, error) {
+	if node.Episode == nil || len(node.Episode.Chapters) == 0 {
+		return nil, fmt.Errorf("%s has no chapters", node.Path)
+	}
+	chapters := make([]map[string]interface{}, 0, len(node.Episode.Chapters))
+	for _, chapter := range node.Episode.Chapters {
+		start, ok := node.chapterStart(chapter)
+		if !ok {
+			continue
+		}
+		item := map[string]interface{}{
+			"startTime": start.Seconds(),
+			"title":     chapter.Title,
+		}
+		if chapter.URL != "" {
+			item["url"] = chapter.URL
+		}
+		if chapter.Image != "" {
+			item["img"] = resolveImageURL(node, chapter.Image)
+		}
+		chapters = append(chapters, item)
+	}
+	return json.Marshal(map[string]interface{}{
+		"version":  "1.2.0",
+		"chapters": chapters,
+	})
+}
+
+// podcastGUID derives the GUID of a podcast from its feed URL, as recommended by the Podcasting 2.0 namespace: a
+// version 5 UUID of the URL without its scheme or trailing slashes.
+func podcastGUID(feedURL string) string {
+	name := strings.TrimRight(feedURL[strings.Index(feedURL, "://")+3:], "/")
+	hash := sha1.New()
+	hash.Write(podcastGUIDNamespace[:])
+	hash.Write([]byte(name))
+	uuid := hash.Sum(nil)[:16]
+	uuid[6] = uuid[6]&0x0F | 0x50
+	uuid[8] = uuid[8]&0x3F | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+var transcriptTypes = map[string]string{
+	".vtt":  "text/vtt",
+	".srt":  "application/srt",
+	".json": "application/json",
+	".html": "text/html",
+	".txt":  "text/plain",
+}
+
+func yesNo(b bool, yes string, no string) string {
+	if b {
+		return yes
+	}
+	return no
+}
+
+// podcastCategories writes Apple Podcasts categories, where "Technology/Tech News" is the subcategory Tech News of
+// Technology.
+func podcastCategories(channel *HTMLElement, categories []string) {
+	for _, category := range categories {
+		parts := strings.SplitN(category, "/", 2)
+		elem := channel.AppendNew("itunes:category", map[string]string{"text": strings.TrimSpace(parts[0])})
+		if len(parts) > 1 {
+			elem.AppendNew("itunes:category", map[string]string{"text": strings.TrimSpace(parts[1])}).SetSelfClosing(true)
+		} else {
+			elem.SetSelfClosing(true)
+		}
+	}
+}
+
+// asPodcastItem describes an episode in a podcast feed.
+func (node *ConfigNode) asPodcastItem() *HTMLElement {
+	episode := node.Episode
+	link := node.pageURL()
+	item := NewHTMLElement("item")
+	item.AppendNew("title").AppendText(html.EscapeString(node.Title))
+	item.AppendNew("link").Compact().AppendText(link)
+	item.AppendNew("guid", map[string]string{"isPermaLink": "false"}).Compact().AppendText(tagURI(node.Tree.Domain, node.Date, node.Path))
+	item.AppendNew("pubDate").AppendText(node.Date.Format(time.RFC1123Z))
+	item.AppendNew("description").AppendText(html.EscapeString(node.Description))
+	item.AppendNew("content:encoded").AppendText(html.EscapeString(node.feedContent())).NoIndent()
+	item.AppendNew("enclosure", map[string]string{
+		"url":    node.Tree.baseURL() + episode.Audio,
+		"length": strconv.FormatInt(episode.Size, 10),
+		"type":   episode.MIMEType,
+	}).SetSelfClosing(true)
+	if episode.Length > 0 {
+		item.AppendNew("itunes:duration").AppendText(strconv.Itoa(int(episode.Length.Round(time.Second) / time.Second)))
+	}
+	if node.Image != "" {
+		item.AppendNew("itunes:image", Href(resolveImageURL(node, node.Image))).SetSelfClosing(true)
+	}
+	item.AppendNew("itunes:explicit").AppendText(yesNo(episode.Explicit, "true", "false"))
+	if episode.Type != "" {
+		item.AppendNew("itunes:episodeType").AppendText(strings.ToLower(episode.Type))
+	}
+	if episode.Season > 0 {
+		item.AppendNew("itunes:season").AppendText(strconv.Itoa(episode.Season))
+		item.AppendNew("podcast:season").AppendText(strconv.Itoa(episode.Season))
+	}
+	if episode.Number > 0 {
+		item.AppendNew("itunes:episode").AppendText(strconv.Itoa(episode.Number))
+		item.AppendNew("podcast:episode").AppendText(strconv.Itoa(episode.Number))
+	}
+	if episode.Transcript != "" {
+		item.AppendNew("podcast:transcript", map[string]string{
+			"url":  resolveImageURL(node, episode.Transcript),
+			"type": transcriptTypes[strings.ToLower(filepath.Ext(episode.Transcript))],
+		}).SetSelfClosing(true)
+	}
+	if len(episode.Chapters) > 0 {
+		// The link of a translated episode already carries the language in its query.
+		sep := "?"
+		if strings.Contains(link, "?") {
+			sep = "&"
+		}
+		item.AppendNew("podcast:chapters", map[string]string{
+			"url":  link + sep + "chapters",
+			"type": "application/json+chapters",
+		}).SetSelfClosing(true)
+		chapters := item.AppendNew("psc:chapters", map[string]string{"version": "1.2"})
+		for _, chapter := range episode.Chapters {
+			start, ok := node.chapterStart(chapter)
+			if !ok {
+				continue
+			}
+			attr := map[string]string{
+				"start": normalPlayTime(start),
+				"title": chapter.Title,
+			}
+			if chapter.URL != "" {
+				attr["href"] = chapter.URL
+			}
+			if chapter.Image != "" {
+				attr["image"] = resolveImageURL(node, chapter.Image)
+			}
+			chapters.AppendNew("psc:chapter", attr).SetSelfClosing(true)
+		}
+	}
+	return item
+}
+
+// renderPodcast writes the RSS feed of a podcast, which carries the iTunes and Podcasting 2.0 tags expected by podcast
+// apps. Only the episodes among items are included.
+func (node *ConfigNode) renderPodcast(f *feedInfo, items []Listable) []byte {
+	var rssXML bytes.Buffer
+	rssXML.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>`)
+	rssXML.WriteByte('\n')
+	feed := NewHTMLElement("rss", map[string]string{
+		"version":       "2.0",
+		"xmlns:atom":    "http://www.w3.org/2005/Atom",
+		"xmlns:content": "http://purl.org/rss/1.0/modules/content/",
+		"xmlns:itunes":  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		"xmlns:podcast": "https://podcastindex.org/namespace/1.0",
+		"xmlns:psc":     "http://podlove.org/simple-chapters",
+	})
+	podcast := node.Podcast
+	feedURL := f.feedURL("podcast.xml")
+	channel := feed.AppendNew("channel")
+	channel.AppendNew("title").AppendText(html.EscapeString(f.Title))
+	channel.AppendNew("description").AppendText(html.EscapeString(f.Description))
+	channel.AppendNew("link").Compact().AppendText(f.Link)
+	channel.AppendNew("atom:link", Href(feedURL), map[string]string{"rel": "self", "type": "application/rss+xml"}).SetSelfClosing(true)
+	if podcast.Language != "" {
+		channel.AppendNew("language").AppendText(podcast.Language)
+	}
+	if f.Copyright != "" {
+		channel.AppendNew("copyright").AppendText(html.EscapeString(f.Copyright))
+	}
+	channel.AppendNew("lastBuildDate").AppendText(time.Now().Format(time.RFC1123Z))
+	channel.AppendNew("pubDate").AppendText(f.Updated.Format(time.RFC1123Z))
+	if f.Author != "" {
+		channel.AppendNew("itunes:author").AppendText(html.EscapeString(f.Author))
+	}
+	if img := node.socialImage(); img.URL != "" {
+		channel.AppendNew("itunes:image", Href(img.URL)).SetSelfClosing(true)
+	}
+	podcastCategories(channel, podcast.Categories)
+	channel.AppendNew("itunes:explicit").AppendText(yesNo(podcast.Explicit, "true", "false"))
+	if podcast.Type != "" {
+		channel.AppendNew("itunes:type").AppendText(strings.ToLower(podcast.Type))
+	}
+	if podcast.Owner.Name != "" || podcast.Owner.Email != "" {
+		owner := channel.AppendNew("itunes:owner")
+		if podcast.Owner.Name != "" {
+			owner.AppendNew("itunes:name").AppendText(html.EscapeString(podcast.Owner.Name))
+		}
+		if podcast.Owner.Email != "" {
+			owner.AppendNew("itunes:email").AppendText(html.EscapeString(podcast.Owner.Email))
+		}
+	}
+	if podcast.Complete {
+		channel.AppendNew("itunes:complete").AppendText("Yes")
+	}
+	guid := podcast.GUID
+	if guid == "" {
+		guid = podcastGUID(feedURL)
+	}
+	channel.AppendNew("podcast:guid").Compact().AppendText(guid)
+	locked := channel.AppendNew("podcast:locked")
+	if podcast.Owner.Email != "" {
+		locked.Attributes["owner"] = podcast.Owner.Email
+	}
+	locked.AppendText(yesNo(podcast.Locked, "yes", "no"))
+	for _, item := range items {
+		if episode, ok := item.(*ConfigNode); ok && episode.Episode != nil {
+			channel.Append(episode.asPodcastItem())
+		}
+	}
+	RenderHTML(feed, &rssXML)
+	return rssXML.Bytes()
+}
@@ -35,17 +35,13 @@ package wyweb
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
 	"time"
 
-	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
-	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
 	meta "github.com/yuin/goldmark-meta"
@@ -126,16 +122,12 @@ func newMarkdown(path string, sourceEmbeds *[]string) goldmark.Markdown {
 			wwExt.AttributeList(),
 			wwExt.LinkRewrite(path),
 			wwExt.AlertExtension(),
+			wwExt.MathExtension(),
 			meta.Meta,
 			extension.GFM,
 			extension.Footnote,
 			extension.Typographer,
-			highlighting.NewHighlighting(
-				highlighting.WithFormatOptions(
-					chromahtml.WithLineNumbers(true),
-					chromahtml.WithClasses(true),
-				),
-			),
+			highlighting.NewHighlighting(highlightingOptions(true)...),
 		),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
@@ -153,22 +145,33 @@ func ParsePost(md goldmark.Markdown, text []byte, path string) ast.Node {
 	return doc
 }
 
-func GetTitleFromMarkdown(node *ConfigNode, text []byte, doc ast.Node) {
-	if text == nil {
-		text, _ = os.ReadFile(node.Index)
+// parsedDocument returns the AST of the post along with its source text, parsing it only if it has not been parsed yet.
+func (node *ConfigNode) parsedDocument() (ast.Node, []byte, error) {
+	if node.ParsedDocument != nil {
+		return *node.ParsedDocument, node.parsedSource, nil
 	}
-	if doc == nil {
-		if node.ParsedDocument != nil {
-			doc = *node.ParsedDocument
-		} else {
-			sourceEmbeds := make([]string, 0)
-			doc = ParsePost(newMarkdown(node.Path, &sourceEmbeds), text, node.Index)
-			for _, s := range sourceEmbeds {
-				node.Dependencies[s] = KindFileEmbed
-			}
-			node.ParsedDocument = &doc
-		}
+	text, err := os.ReadFile(node.Index)
+	if err != nil {
+		return nil, nil, err
+	}
+	sourceEmbeds := make([]string, 0)
+	doc := ParsePost(newMarkdown(node.Path, &sourceEmbeds), text, node.Index)
+	for _, s := range sourceEmbeds {
+		node.Dependencies[s] = KindFileEmbed
 	}
+	node.cacheDocument(doc, text)
+	return doc, text, nil
+}
+
+// cacheDocument keeps the AST of the post along with the text it was parsed from. The file may have changed since,
+// so anything using the AST must take its text from the cache as well.
+func (node *ConfigNode) cacheDocument(doc ast.Node, text []byte) {
+	node.ParsedDocument = &doc
+	node.parsedSource = text
+}
+
+// findTitleHeading returns the first top level heading of the document, or nil if there is none.
+func findTitleHeading(doc ast.Node) ast.Node {
 	titleNode := doc.FirstChild()
 	for titleNode != nil {
 		if titleNode.Kind() == ast.KindHeading && titleNode.(*ast.Heading).Level == 1 {
@@ -176,6 +179,25 @@ func GetTitleFromMarkdown(node *ConfigNode, text []byte, doc ast.Node) {
 		}
 		titleNode = titleNode.NextSibling()
 	}
+	return titleNode
+}
+
+func GetTitleFromMarkdown(node *ConfigNode, text []byte, doc ast.Node) {
+	if doc == nil && node.ParsedDocument != nil {
+		doc, text = *node.ParsedDocument, node.parsedSource
+	}
+	if text == nil {
+		text, _ = os.ReadFile(node.Index)
+	}
+	if doc == nil {
+		sourceEmbeds := make([]string, 0)
+		doc = ParsePost(newMarkdown(node.Path, &sourceEmbeds), text, node.Index)
+		for _, s := range sourceEmbeds {
+			node.Dependencies[s] = KindFileEmbed
+		}
+		node.cacheDocument(doc, text)
+	}
+	titleNode := findTitleHeading(doc)
 	if titleNode != nil {
 		h1Node := titleNode.(*ast.Heading)
 		txt := string(h1Node.Text(text))
@@ -191,10 +213,10 @@ func GetPreviewFromMarkdown(node *ConfigNode, text []byte, doc ast.Node) {
 	}
 	if doc == nil {
 		if node.ParsedDocument != nil {
-			doc = *node.ParsedDocument
+			doc, text = *node.ParsedDocument, node.parsedSource
 		} else {
 			doc = ParsePost(md, text, node.Index)
-			node.ParsedDocument = &doc
+			node.cacheDocument(doc, text)
 		}
 	}
 	md.Renderer().AddOptions(
@@ -213,28 +235,27 @@ func MDConvertPost(text []byte, node *ConfigNode) (bytes.Buffer, *HTMLElement, *
 	//node.Lock()
 	//defer node.Unlock()
 	defer util.Timer("mdConvert")()
-	StyleName := "catppuccin-mocha"
 	sourceEmbeds := make([]string, 0)
 	md := newMarkdown(node.Path, &sourceEmbeds)
+	// Replaces the highlighting renderer of newMarkdown with one numbering lines as the page asks.
+	md.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			gmUtil.Prioritized(highlighting.NewHTMLRenderer(highlightingOptions(node.Highlight.ShowLineNumbers())...), 199),
+		),
+	)
 	var doc ast.Node
 	if node.ParsedDocument != nil {
-		doc = *node.ParsedDocument
+		doc, text = *node.ParsedDocument, node.parsedSource
 	} else {
 		doc = ParsePost(md, text, node.Index)
-		node.ParsedDocument = &doc
+		node.cacheDocument(doc, text)
 	}
 	for _, s := range sourceEmbeds {
 		node.Dependencies[s] = KindFileEmbed
 	}
 	renderedToc := renderTOC(&doc, text)
 
-	titleNode := doc.FirstChild()
-	for titleNode != nil {
-		if titleNode.Kind() == ast.KindHeading && titleNode.(*ast.Heading).Level == 1 {
-			break
-		}
-		titleNode = titleNode.NextSibling()
-	}
+	titleNode := findTitleHeading(doc)
 	title := NewHTMLElement("h1", ID("title"))
 	if titleNode != nil {
 		h1Node := titleNode.(*ast.Heading)
@@ -255,35 +276,8 @@ func MDConvertPost(text []byte, node *ConfigNode) (bytes.Buffer, *HTMLElement, *
 		panic(err)
 	}
 
-	formatter := chromahtml.New(chromahtml.WithClasses(true))
-	if _, ok := node.Tree.Resources[StyleName]; !ok {
-		var css bytes.Buffer
-		style := styles.Get(StyleName)
-		formatter.WriteCSS(&css, style)
-		node.Tree.Resources[StyleName] = Resource{
-			Type:       "style",
-			Method:     "raw",
-			Value:      css.String(),
-			Attributes: map[string]string{"media": "screen"},
-		}
-	}
-	if _, ok := node.Tree.Resources["algol"]; !ok {
-		var css bytes.Buffer
-		style := styles.Get("algol")
-		formatter.WriteCSS(&css, style)
-		node.Tree.Resources["algol"] = Resource{
-			Type:       "style",
-			Method:     "raw",
-			Value:      css.String(),
-			Attributes: map[string]string{"media": "print"},
-		}
-	}
-	if !slices.Contains(node.LocalResources, StyleName) {
-		node.LocalResources = append(node.LocalResources, StyleName)
-	}
-	if !slices.Contains(node.LocalResources, "algol") {
-		node.LocalResources = append(node.LocalResources, "algol")
-	}
+	node.features = scanFeatures(doc, text)
+	node.forgetResourceOrder()
 
 	return buf, renderedToc, title, err
 }
@@ -298,16 +292,16 @@ func buildArticleHeader(node *ConfigNode, title, crumbs, article *HTMLElement) {
 	info.AppendNew("time",
 		ID("publication-date"),
 		map[string]string{"datetime": node.Date.Format(time.DateOnly)},
-	).AppendText(node.Date.Format("Jan _2, 2006"))
+	).AppendText(node.FormatDate(node.Date))
 	info.AppendNew("span", ID("author")).AppendText(node.Author)
 	info.AppendNew("time",
 		ID("updated"),
 		map[string]string{"datetime": node.Updated.Format(time.RFC3339)},
-	).AppendText(node.Updated.Format("Jan _2, 2006"))
+	).AppendText(node.FormatDate(node.Updated))
 	header.Append(BuildNavlinks(node))
 }
 
-func findIndex(path string) ([]byte, error) {
+func findIndexPath(path string) (string, error) {
 	tryFiles := []string{
 		"article.md",
 		"index.md",
@@ -320,26 +314,50 @@ func findIndex(path string) ([]byte, error) {
 		index := filepath.Join(path, f)
 		_, err := os.Stat(index)
 		if err == nil {
-			return os.ReadFile(index)
+			return index, nil
 		}
 	}
-	return nil, fmt.Errorf("could not find index")
+	return "", fmt.Errorf("could not find index")
+}
+
+func findIndex(path string) ([]byte, error) {
+	index, err := findIndexPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(index)
+}
+
+func buildPostBody(node *ConfigNode, content string, TOC, title, crumbs *HTMLElement) *HTMLElement {
+	body := NewHTMLElement("body")
+	body.Append(TOC)
+	article := body.AppendNew("article")
+	buildArticleHeader(node, title, crumbs, article)
+	if !node.Series.IsZero() {
+		article.Append(BuildSeriesBox(node))
+	}
+	if node.Episode != nil {
+		article.Append(BuildEpisodePlayer(node))
+	}
+	article.AppendText(content).NoIndent()
+	if node.Recipe != nil {
+		article.Append(BuildRecipeCard(node, 0, ""))
+	}
+	tagcontainer := article.AppendNew("div", Class("tag-container"))
+	tagcontainer.AppendText(node.T("Tags"))
+	taglist := tagcontainer.AppendNew("div", Class("tag-list"))
+	for _, tag := range node.Tags {
+		taglist.AppendNew("a", Class("tag-link"), Href("/"+filepath.Join(node.Parent.Path, "?tags=")+tag)).AppendText(tag)
+	}
+	if !node.Related.IsZero() {
+		article.Append(BuildRelated(node))
+	}
+	return body
 }
 
 func BuildPost(node *ConfigNode) error {
 	//node.RLock()
 	//defer node.RUnlock()
-	structuredData := map[string]interface{}{
-		"@context": "https://schema.org",
-		"@type":    "BlogPosting",
-		"author": map[string]interface{}{
-			"@type": "person",
-			"name":  node.Author,
-		},
-		"headline":      node.Title,
-		"datePublished": node.Date.Format(time.DateOnly),
-		"dateUpdated":   node.Updated.Format(time.DateOnly),
-	}
 	resolved := node
 	var mdtext []byte
 	var err error
@@ -351,21 +369,17 @@ func BuildPost(node *ConfigNode) error {
 		return err
 	}
 	temp, TOC, title, _ := MDConvertPost(mdtext, node)
-	body := NewHTMLElement("body")
-	body.Append(TOC)
-	article := body.AppendNew("article")
 	crumbs, bcSD := Breadcrumbs(node)
-	buildArticleHeader(node, title, crumbs, article)
-	article.AppendText(temp.String()).NoIndent()
-	tagcontainer := article.AppendNew("div", Class("tag-container"))
-	tagcontainer.AppendText("Tags")
-	taglist := tagcontainer.AppendNew("div", Class("tag-list"))
-	for _, tag := range node.Tags {
-		taglist.AppendNew("a", Class("tag-link"), Href("/"+filepath.Join(node.Parent.Path, "?tags=")+tag)).AppendText(tag)
+	if themed, ok := node.themedPost("post", temp.String(), TOC, crumbs); ok {
+		resolved.HTML = themed
+	} else {
+		resolved.HTML = buildPostBody(node, temp.String(), TOC, title, crumbs)
 	}
-	resolved.HTML = body
-	jsonld, _ := json.MarshalIndent(structuredData, "", "    ")
 	node.StructuredData = append(node.StructuredData, bcSD)
-	node.StructuredData = append(node.StructuredData, string(jsonld))
+	if node.Recipe != nil {
+		node.StructuredData = append(node.StructuredData, node.recipeStructuredData())
+	} else {
+		node.StructuredData = append(node.StructuredData, node.postStructuredData())
+	}
 	return nil
 }
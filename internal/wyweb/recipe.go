@@ -0,0 +1,621 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	recipeCardStart = "<!-- recipe-card -->"
+	recipeCardEnd   = "<!-- /recipe-card -->"
+)
+
+type unitDimension int
+
+const (
+	unitVolume unitDimension = iota
+	unitMass
+)
+
+// A unit of measure that recipes can be converted to and from. Size is in millilitres for volumes and grams for
+// masses.
+type recipeUnit struct {
+	Name      string
+	Plural    string
+	Dimension unitDimension
+	Size      float64
+	Metric    bool
+}
+
+var (
+	unitMillilitre = recipeUnit{"ml", "ml", unitVolume, 1, true}
+	unitLitre      = recipeUnit{"l", "l", unitVolume, 1000, true}
+	unitTeaspoon   = recipeUnit{"tsp", "tsp", unitVolume, 4.92892, false}
+	unitTablespoon = recipeUnit{"tbsp", "tbsp", unitVolume, 14.7868, false}
+	unitFluidOunce = recipeUnit{"fl oz", "fl oz", unitVolume, 29.5735, false}
+	unitCup        = recipeUnit{"cup", "cups", unitVolume, 236.588, false}
+	unitPint       = recipeUnit{"pint", "pints", unitVolume, 473.176, false}
+	unitQuart      = recipeUnit{"quart", "quarts", unitVolume, 946.353, false}
+	unitGallon     = recipeUnit{"gallon", "gallons", unitVolume, 3785.41, false}
+	unitGram       = recipeUnit{"g", "g", unitMass, 1, true}
+	unitKilogram   = recipeUnit{"kg", "kg", unitMass, 1000, true}
+	unitOunce      = recipeUnit{"oz", "oz", unitMass, 28.3495, false}
+	unitPound      = recipeUnit{"lb", "lb", unitMass, 453.592, false}
+)
+
+// Every spelling of a unit that is recognized, in lower case.
+var recipeUnits = map[string]recipeUnit{
+	"ml":           unitMillilitre,
+	"millilitre":   unitMillilitre,
+	"millilitres":  unitMillilitre,
+	"milliliter":   unitMillilitre,
+	"milliliters":  unitMillilitre,
+	"l":            unitLitre,
+	"litre":        unitLitre,
+	"litres":       unitLitre,
+	"liter":        unitLitre,
+	"liters":       unitLitre,
+	"tsp":          unitTeaspoon,
+	"teaspoon":     unitTeaspoon,
+	"teaspoons":    unitTeaspoon,
+	"tbsp":         unitTablespoon,
+	"tablespoon":   unitTablespoon,
+	"tablespoons":  unitTablespoon,
+	"fl oz":        unitFluidOunce,
+	"fluid ounce":  unitFluidOunce,
+	"fluid ounces": unitFluidOunce,
+	"cup":          unitCup,
+	"cups":         unitCup,
+	"pint":         unitPint,
+	"pints":        unitPint,
+	"quart":        unitQuart,
+	"quarts":       unitQuart,
+	"gallon":       unitGallon,
+	"gallons":      unitGallon,
+	"g":            unitGram,
+	"gram":         unitGram,
+	"grams":        unitGram,
+	"kg":           unitKilogram,
+	"kilogram":     unitKilogram,
+	"kilograms":    unitKilogram,
+	"oz":           unitOunce,
+	"ounce":        unitOunce,
+	"ounces":       unitOunce,
+	"lb":           unitPound,
+	"lbs":          unitPound,
+	"pound":        unitPound,
+	"pounds":       unitPound,
+}
+
+// The units an amount may be converted to, largest first. The first unit that the amount fills at least Minimum of
+// is used.
+var unitTargets = map[string]map[unitDimension][]struct {
+	Unit    recipeUnit
+	Minimum float64
+}{
+	"metric": {
+		unitVolume: {{unitLitre, 1}, {unitMillilitre, 0}},
+		unitMass:   {{unitKilogram, 1}, {unitGram, 0}},
+	},
+	"us": {
+		unitVolume: {{unitGallon, 1}, {unitCup, 0.25}, {unitTablespoon, 1}, {unitTeaspoon, 0}},
+		unitMass:   {{unitPound, 1}, {unitOunce, 0}},
+	},
+}
+
+var vulgarFractions = []struct {
+	Value float64
+	Glyph string
+}{
+	{1.0 / 8, "⅛"},
+	{1.0 / 4, "¼"},
+	{1.0 / 3, "⅓"},
+	{3.0 / 8, "⅜"},
+	{1.0 / 2, "½"},
+	{5.0 / 8, "⅝"},
+	{2.0 / 3, "⅔"},
+	{3.0 / 4, "¾"},
+	{7.0 / 8, "⅞"},
+}
+
+// parseQuantity reads a single amount, which may be a whole number, a decimal, a fraction, a mixed number such as
+// "1 1/2", or may use a vulgar fraction such as "1½".
+func parseQuantity(text string) (float64, bool) {
+	for _, fraction := range vulgarFractions {
+		text = strings.ReplaceAll(text, fraction.Glyph, " "+strconv.FormatFloat(fraction.Value, 'f', -1, 64))
+	}
+	total := 0.0
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	for _, field := range fields {
+		if num, denom, ok := strings.Cut(field, "/"); ok {
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, false
+			}
+			d, err := strconv.ParseFloat(denom, 64)
+			if err != nil || d == 0 {
+				return 0, false
+			}
+			total += n / d
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += value
+	}
+	return total, true
+}
+
+// parseQuantityRange reads a quantity that may be a range such as "2-3", giving one value for a single amount or two
+// for a range.
+func parseQuantityRange(text string) ([]float64, bool) {
+	parts := strings.FieldsFunc(text, func(r rune) bool { return r == '-' || r == '–' })
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, false
+	}
+	out := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		value, ok := parseQuantity(part)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, value)
+	}
+	return out, true
+}
+
+// formatQuantity writes an amount for display. Metric amounts are given as decimals, while everything else is
+// given as a mixed number using the nearest common fraction, as is usual in recipes.
+func formatQuantity(value float64, metric bool) string {
+	if metric {
+		precision := 0
+		if value < 10 {
+			precision = 1
+		}
+		if value < 1 {
+			precision = 2
+		}
+		out := strconv.FormatFloat(value, 'f', precision, 64)
+		if strings.Contains(out, ".") {
+			out = strings.TrimRight(strings.TrimRight(out, "0"), ".")
+		}
+		return out
+	}
+	whole := math.Floor(value)
+	rest := value - whole
+	glyph := ""
+	best := rest
+	roundUp := false
+	if 1-rest < best {
+		best = 1 - rest
+		roundUp = true
+	}
+	for _, fraction := range vulgarFractions {
+		if diff := math.Abs(rest - fraction.Value); diff < best {
+			best = diff
+			glyph = fraction.Glyph
+			roundUp = false
+		}
+	}
+	if best > 1.0/16 {
+		return strings.TrimRight(strings.TrimRight(strconv.FormatFloat(value, 'f', 2, 64), "0"), ".")
+	}
+	if roundUp {
+		whole++
+	}
+	if whole == 0 && glyph != "" {
+		return glyph
+	}
+	return strconv.FormatFloat(whole, 'f', 0, 64) + glyph
+}
+
+// convertAmount expresses an amount in the given system of units ("metric" or "us"). Units that are not known,
+// such as "clove" or "pinch", are left as they are.
+func convertAmount(values []float64, unit string, system string) ([]float64, string, bool) {
+	from, known := recipeUnits[strings.ToLower(unit)]
+	targets, ok := unitTargets[system]
+	if !known || !ok {
+		return values, unit, false
+	}
+	base := values[len(values)-1] * from.Size
+	target := targets[from.Dimension][len(targets[from.Dimension])-1].Unit
+	for _, candidate := range targets[from.Dimension] {
+		if base/candidate.Unit.Size >= candidate.Minimum {
+			target = candidate.Unit
+			break
+		}
+	}
+	out := make([]float64, len(values))
+	for idx, value := range values {
+		out[idx] = value * from.Size / target.Size
+	}
+	if out[len(out)-1] > 1 {
+		return out, target.Plural, target.Metric
+	}
+	return out, target.Name, target.Metric
+}
+
+// scaledIngredient gives the text of an ingredient's amount after scaling it by factor and converting it to the
+// given system of units. Quantities that cannot be read, such as "a handful", are shown unchanged.
+func scaledIngredient(ingredient WWIngredient, factor float64, system string) string {
+	values, ok := parseQuantityRange(ingredient.Quantity)
+	if !ok {
+		return strings.TrimSpace(ingredient.Quantity + " " + ingredient.Unit)
+	}
+	for idx := range values {
+		values[idx] *= factor
+	}
+	values, unit, metric := convertAmount(values, ingredient.Unit, system)
+	if known, ok := recipeUnits[strings.ToLower(unit)]; ok && system == "" {
+		metric = known.Metric
+	}
+	parts := make([]string, len(values))
+	for idx, value := range values {
+		parts[idx] = formatQuantity(value, metric)
+	}
+	return strings.TrimSpace(strings.Join(parts, "–") + " " + unit)
+}
+
+// String gives the ingredient as written, e.g. "2 cups flour, sifted".
+func (i WWIngredient) String() string {
+	out := strings.TrimSpace(strings.Join([]string{i.Quantity, i.Unit, i.Item}, " "))
+	out = strings.Join(strings.Fields(out), " ")
+	if i.Note != "" {
+		out += ", " + i.Note
+	}
+	return out
+}
+
+// parseRecipeDuration reads a preparation or cooking time, which is either a duration such as 1h30m or a whole
+// number of minutes.
+func parseRecipeDuration(text string) (time.Duration, bool) {
+	if text == "" {
+		return 0, false
+	}
+	if minutes, err := strconv.Atoi(text); err == nil {
+		return time.Duration(minutes) * time.Minute, true
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		log.Printf("WARN: could not read recipe time %s", text)
+		return 0, false
+	}
+	return d, true
+}
+
+func formatRecipeDuration(d time.Duration) string {
+	hours := int(d / time.Hour)
+	minutes := int(d/time.Minute) % 60
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%d hr %d min", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%d hr", hours)
+	default:
+		return fmt.Sprintf("%d min", minutes)
+	}
+}
+
+func (r *WWRecipe) times() (prep time.Duration, cook time.Duration, total time.Duration) {
+	prep, _ = parseRecipeDuration(r.PrepTime)
+	cook, _ = parseRecipeDuration(r.CookTime)
+	return prep, cook, prep + cook
+}
+
+func (r *WWRecipe) yield() string {
+	if r.Yield != "" {
+		return r.Yield
+	}
+	if r.Servings > 0 {
+		return fmt.Sprintf("%d servings", r.Servings)
+	}
+	return ""
+}
+
+// summary describes the recipe in a sentence, for use as a preview when nothing else is available.
+func (r *WWRecipe) summary() string {
+	parts := make([]string, 0, 2)
+	if yield := r.yield(); yield != "" {
+		parts = append(parts, "Makes "+yield)
+	}
+	if _, _, total := r.times(); total > 0 {
+		parts = append(parts, "ready in "+formatRecipeDuration(total))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, ", ") + "."
+}
+
+// Nutrition facts in the order they are shown, along with their schema.org property names.
+func (n WWNutrition) facts() []struct{ Label, Property, Value string } {
+	return []struct{ Label, Property, Value string }{
+		{"Calories", "calories", n.Calories},
+		{"Fat", "fatContent", n.Fat},
+		{"Saturated fat", "saturatedFatContent", n.SaturatedFat},
+		{"Cholesterol", "cholesterolContent", n.Cholesterol},
+		{"Sodium", "sodiumContent", n.Sodium},
+		{"Carbohydrates", "carbohydrateContent", n.Carbohydrates},
+		{"Fiber", "fiberContent", n.Fiber},
+		{"Sugar", "sugarContent", n.Sugar},
+		{"Protein", "proteinContent", n.Protein},
+	}
+}
+
+// RecipeOptions reads the servings and units a reader asked for from the query string. ok is false if the recipe
+// should be shown as written.
+func RecipeOptions(query url.Values) (servings int, units string, ok bool) {
+	servings, _ = strconv.Atoi(query.Get("servings"))
+	if servings < 0 {
+		servings = 0
+	}
+	units = query.Get("units")
+	if _, known := unitTargets[units]; !known {
+		units = ""
+	}
+	return servings, units, servings > 0 || units != ""
+}
+
+func recipeMetaItem(list *HTMLElement, label string, value string, attr ...map[string]string) {
+	if value == "" {
+		return
+	}
+	list.AppendNew("dt").AppendText(label)
+	list.AppendNew("dd", attr...).AppendText(html.EscapeString(value))
+}
+
+// BuildRecipeCard renders the structured part of a recipe. If servings is given, the ingredients are scaled from
+// the servings the recipe was written for, and if units is "metric" or "us" they are converted to that system.
+func BuildRecipeCard(node *ConfigNode, servings int, units string) *HTMLElement {
+	recipe := node.Recipe
+	card := NewHTMLElement("section", Class("recipe-card"))
+	meta := card.AppendNew("dl", Class("recipe-meta"))
+	prep, cook, total := recipe.times()
+	if prep > 0 {
+		recipeMetaItem(meta, node.T("Prep"), formatRecipeDuration(prep))
+	}
+	if cook > 0 {
+		recipeMetaItem(meta, node.T("Cook"), formatRecipeDuration(cook))
+	}
+	if prep > 0 && cook > 0 {
+		recipeMetaItem(meta, node.T("Total"), formatRecipeDuration(total))
+	}
+	factor := 1.0
+	yield := recipe.yield()
+	if servings > 0 && recipe.Servings > 0 {
+		factor = float64(servings) / float64(recipe.Servings)
+		if factor != 1 {
+			yield = node.T("%d servings", servings)
+		}
+	}
+	recipeMetaItem(meta, node.T("Yield"), yield)
+	recipeMetaItem(meta, node.T("Category"), recipe.Category)
+	recipeMetaItem(meta, node.T("Cuisine"), recipe.Cuisine)
+
+	if len(recipe.Ingredients) > 0 {
+		card.AppendNew("h2").AppendText(node.T("Ingredients"))
+		// A plain GET form lets readers rescale the recipe without any scripts.
+		form := card.AppendNew("form", Class("recipe-scale"), map[string]string{"method": "get"})
+		if node.Lang != "" {
+			// The query of the page is replaced by the form's, so the language has to be sent along with it.
+			form.AppendNew("input", map[string]string{"type": "hidden", "name": "lang", "value": node.Lang}).SetSelfClosing(true)
+		}
+		if recipe.Servings > 0 {
+			label := form.AppendNew("label")
+			label.AppendText(node.T("Servings"))
+			shown := recipe.Servings
+			if servings > 0 {
+				shown = servings
+			}
+			input := label.AppendNew("input", map[string]string{
+				"type":  "number",
+				"name":  "servings",
+				"min":   "1",
+				"value": strconv.Itoa(shown),
+			})
+			input.SetSelfClosing(true)
+		}
+		label := form.AppendNew("label")
+		label.AppendText(node.T("Units"))
+		sel := label.AppendNew("select", map[string]string{"name": "units"})
+		for _, option := range []struct{ Value, Text string }{{"", "As written"}, {"metric", "Metric"}, {"us", "US"}} {
+			opt := sel.AppendNew("option", map[string]string{"value": option.Value})
+			if option.Value == units {
+				opt.Attributes["selected"] = "selected"
+			}
+			opt.AppendText(node.T(option.Text))
+		}
+		form.AppendNew("button", map[string]string{"type": "submit"}).AppendText(node.T("Update"))
+		list := card.AppendNew("ul", Class("recipe-ingredients"))
+		for _, ingredient := range recipe.Ingredients {
+			item := list.AppendNew("li")
+			if amount := scaledIngredient(ingredient, factor, units); amount != "" {
+				item.AppendNew("span", Class("ingredient-amount")).AppendText(html.EscapeString(amount))
+			}
+			item.AppendNew("span", Class("ingredient-item")).AppendText(html.EscapeString(ingredient.Item))
+			if ingredient.Note != "" {
+				item.AppendNew("span", Class("ingredient-note")).AppendText(html.EscapeString(ingredient.Note))
+			}
+		}
+	}
+
+	if len(recipe.Steps) > 0 {
+		card.AppendNew("h2").AppendText(node.T("Method"))
+		steps := card.AppendNew("ol", Class("recipe-steps"))
+		md := newMarkdown(node.Path, nil)
+		for idx, step := range recipe.Steps {
+			item := steps.AppendNew("li", ID(fmt.Sprintf("step-%d", idx+1)))
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(step), &buf); err != nil {
+				log.Println(err.Error())
+				item.AppendText(html.EscapeString(step))
+				continue
+			}
+			// A step of a single paragraph needs no paragraph of its own within the list item.
+			text := strings.TrimSpace(buf.String())
+			if strings.Count(text, "<p>") == 1 && strings.HasPrefix(text, "<p>") && strings.HasSuffix(text, "</p>") {
+				text = strings.TrimSuffix(strings.TrimPrefix(text, "<p>"), "</p>")
+			}
+			item.AppendText(text)
+		}
+	}
+
+	facts := recipe.Nutrition.facts()
+	nutrition := NewHTMLElement("dl", Class("recipe-nutrition"))
+	for _, fact := range facts {
+		recipeMetaItem(nutrition, node.T(fact.Label), fact.Value)
+	}
+	if len(nutrition.Children) > 0 {
+		card.AppendNew("h2").AppendText(node.T("Nutrition per serving"))
+		card.Append(nutrition)
+	}
+	return card
+}
+
+// RescaledRecipe gives the page of a recipe with its card rebuilt for the given servings and units, leaving the
+// cached page as written.
+func RescaledRecipe(node *ConfigNode, servings int, units string) *HTMLElement {
+	page := node.HTML.Clone()
+	if card, err := page.FirstElementByClass("recipe-card"); err == nil {
+		*card = *BuildRecipeCard(node, servings, units)
+		return page
+	}
+	// A page rendered from a theme holds the card as markup, so the markup between its markers is swapped instead.
+	var rescaled bytes.Buffer
+	RenderHTML(BuildRecipeCard(node, servings, units), &rescaled)
+	var dft func(*HTMLElement)
+	dft = func(elem *HTMLElement) {
+		before, rest, found := strings.Cut(elem.Content, recipeCardStart)
+		if elem.Tag == "" && found {
+			if _, after, ok := strings.Cut(rest, recipeCardEnd); ok {
+				elem.Content = before + recipeCardStart + rescaled.String() + recipeCardEnd + after
+			}
+		}
+		for _, child := range elem.Children {
+			dft(child)
+		}
+	}
+	dft(page)
+	return page
+}
+
+// recipeStructuredData describes a recipe as a schema.org Recipe.
+func (node *ConfigNode) recipeStructuredData() string {
+	recipe := node.Recipe
+	url := node.pageURL()
+	data := map[string]interface{}{
+		"@context":         "https://schema.org",
+		"@type":            "Recipe",
+		"name":             node.Title,
+		"url":              url,
+		"datePublished":    node.Date.Format(time.RFC3339),
+		"dateModified":     node.Updated.Format(time.RFC3339),
+		"mainEntityOfPage": map[string]interface{}{"@type": "WebPage", "@id": url},
+		"isPartOf":         map[string]interface{}{"@id": node.Tree.websiteID()},
+		"publisher":        node.Tree.publisherData(),
+	}
+	if node.Author != "" {
+		data["author"] = personData(node.Author)
+	}
+	if node.Description != "" {
+		data["description"] = node.Description
+	}
+	if img := node.socialImage(); img.URL != "" {
+		data["image"] = img.URL
+	}
+	if len(node.Tags) > 0 {
+		data["keywords"] = strings.Join(node.Tags, ", ")
+	}
+	if recipe.Category != "" {
+		data["recipeCategory"] = recipe.Category
+	}
+	if recipe.Cuisine != "" {
+		data["recipeCuisine"] = recipe.Cuisine
+	}
+	if yield := recipe.yield(); yield != "" {
+		data["recipeYield"] = yield
+	}
+	prep, cook, total := recipe.times()
+	if prep > 0 {
+		data["prepTime"] = isoDuration(prep)
+	}
+	if cook > 0 {
+		data["cookTime"] = isoDuration(cook)
+	}
+	if total > 0 {
+		data["totalTime"] = isoDuration(total)
+	}
+	ingredients := make([]string, 0, len(recipe.Ingredients))
+	for _, ingredient := range recipe.Ingredients {
+		ingredients = append(ingredients, ingredient.String())
+	}
+	if len(ingredients) > 0 {
+		data["recipeIngredient"] = ingredients
+	}
+	steps := make([]map[string]interface{}, 0, len(recipe.Steps))
+	for idx, step := range recipe.Steps {
+		steps = append(steps, map[string]interface{}{
+			"@type": "HowToStep",
+			"text":  step,
+			"url":   fmt.Sprintf("%s#step-%d", url, idx+1),
+		})
+	}
+	if len(steps) > 0 {
+		data["recipeInstructions"] = steps
+	}
+	nutrition := map[string]interface{}{"@type": "NutritionInformation"}
+	for _, fact := range recipe.Nutrition.facts() {
+		if fact.Value != "" {
+			nutrition[fact.Property] = fact.Value
+		}
+	}
+	if len(nutrition) > 1 {
+		data["nutrition"] = nutrition
+	}
+	return marshalStructuredData(data)
+}
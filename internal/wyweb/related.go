@@ -0,0 +1,237 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+const defaultRelatedCount = 5
+
+// Shared words carry less weight than shared tags. A perfect textual match is worth about as much as one rare tag.
+const relatedContentWeight = 2.0
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true, "you": true, "all": true,
+	"any": true, "can": true, "had": true, "her": true, "was": true, "one": true, "our": true, "out": true,
+	"has": true, "his": true, "how": true, "its": true, "may": true, "new": true, "now": true, "see": true,
+	"who": true, "did": true, "get": true, "him": true, "let": true, "say": true, "she": true, "too": true,
+	"use": true, "that": true, "with": true, "have": true, "this": true, "will": true, "your": true,
+	"from": true, "they": true, "been": true, "were": true, "what": true, "when": true, "which": true,
+	"their": true, "there": true, "would": true, "about": true, "into": true, "than": true, "then": true,
+	"them": true, "these": true, "some": true, "could": true, "other": true, "also": true, "just": true,
+}
+
+// relatedCandidates returns every other post within the scope configured for node.
+func (node *ConfigNode) relatedCandidates() []*ConfigNode {
+	top := node.Tree.Root
+	if node.Related.Scope == "listing" && node.Parent != nil {
+		top = node.Parent
+	}
+	out := make([]*ConfigNode, 0)
+	var dft func(*ConfigNode)
+	dft = func(n *ConfigNode) {
+		if n.isArticle() && n != node && n.Path != node.Path && !n.Draft {
+			out = append(out, n)
+		}
+		for _, child := range n.Children {
+			dft(child)
+		}
+	}
+	dft(top)
+	return out
+}
+
+// documentTerms counts the words of the post, ignoring very short and very common ones. The result is normalized to
+// unit length so that the dot product of two term vectors is their cosine similarity.
+func (node *ConfigNode) documentTerms() map[string]float64 {
+	if node.terms != nil {
+		return node.terms
+	}
+	node.terms = make(map[string]float64)
+	doc, text, err := node.parsedDocument()
+	if err != nil {
+		return node.terms
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			words := strings.FieldsFunc(string(t.Segment.Value(text)), func(r rune) bool {
+				return !unicode.IsLetter(r)
+			})
+			for _, word := range words {
+				word = strings.ToLower(word)
+				if len(word) < 3 || stopWords[word] {
+					continue
+				}
+				node.terms[word]++
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	var norm float64
+	for _, count := range node.terms {
+		norm += count * count
+	}
+	norm = math.Sqrt(norm)
+	for word := range node.terms {
+		node.terms[word] /= norm
+	}
+	return node.terms
+}
+
+func termSimilarity(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot float64
+	for word, weight := range a {
+		dot += weight * b[word]
+	}
+	return dot
+}
+
+// computeRelated ranks candidates by the tags they share with node. Each shared tag is weighted by its rarity, so two
+// posts sharing an obscure tag are considered closer than two posts sharing a common one.
+func (node *ConfigNode) computeRelated() []*ConfigNode {
+	candidates := node.relatedCandidates()
+	tagdb := node.Tree.TagDB
+	if node.Related.Scope == "listing" && node.Parent != nil && node.Parent != node.Tree.Root {
+		tagdb = node.Parent.TagDB
+	}
+	total := float64(len(candidates) + 1)
+	type scored struct {
+		node  *ConfigNode
+		score float64
+	}
+	ranking := make([]scored, 0, len(candidates))
+	for _, other := range candidates {
+		var score float64
+		for _, tag := range node.Tags {
+			if !slices.Contains(other.Tags, tag) {
+				continue
+			}
+			frequency := float64(len(tagdb[tag]))
+			if frequency < 1 {
+				frequency = 1
+			}
+			score += math.Log(1 + total/frequency)
+		}
+		if node.Related.Content {
+			score += relatedContentWeight * termSimilarity(node.documentTerms(), other.documentTerms())
+		}
+		if score > 0 {
+			ranking = append(ranking, scored{other, score})
+		}
+	}
+	sort.SliceStable(ranking, func(i, j int) bool {
+		if ranking[i].score == ranking[j].score {
+			return ranking[i].node.Date.After(ranking[j].node.Date)
+		}
+		return ranking[i].score > ranking[j].score
+	})
+	count := node.Related.Count
+	if count <= 0 {
+		count = defaultRelatedCount
+	}
+	if len(ranking) > count {
+		ranking = ranking[:count]
+	}
+	out := make([]*ConfigNode, len(ranking))
+	for i, r := range ranking {
+		out[i] = r.node
+	}
+	return out
+}
+
+// GetRelated returns the posts related to node, computing them only if they are not already cached.
+func (node *ConfigNode) GetRelated() []*ConfigNode {
+	tree := node.Tree
+	tree.relatedLock.Lock()
+	defer tree.relatedLock.Unlock()
+	if related, ok := tree.related[node.Path]; ok {
+		return related
+	}
+	related := node.computeRelated()
+	tree.related[node.Path] = related
+	return related
+}
+
+// invalidateRelated empties the cache and discards every post showing related posts, so that they are computed again
+// when the post is next requested.
+func (tree *ConfigTree) invalidateRelated() {
+	tree.relatedLock.Lock()
+	tree.related = make(map[string][]*ConfigNode)
+	tree.relatedLock.Unlock()
+	var dft func(*ConfigNode)
+	dft = func(node *ConfigNode) {
+		if node.isArticle() && !node.Related.IsZero() {
+			node.invalidate()
+		}
+		for _, child := range node.Children {
+			dft(child)
+		}
+	}
+	dft(tree.Root)
+}
+
+func BuildRelated(node *ConfigNode) *HTMLElement {
+	aside := NewHTMLElement("aside", Class("related-posts"), AriaLabel(node.T("Related")))
+	related := node.GetRelated()
+	if len(related) == 0 {
+		return aside
+	}
+	aside.AppendNew("h2").AppendText(node.T("Related"))
+	ul := aside.AppendNew("ul")
+	for _, other := range related {
+		li := ul.AppendNew("li")
+		li.AppendNew("a", Href("/"+other.Path)).AppendText(other.titleIn(node.Lang))
+		li.AppendNew("time",
+			map[string]string{"datetime": other.Date.Format(time.DateOnly)},
+		).AppendText(node.FormatDate(other.Date))
+	}
+	return aside
+}
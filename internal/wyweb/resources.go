@@ -0,0 +1,286 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A link in the head telling the browser to fetch a resource early, either because it is needed soon (preload) or
+// because the next page will need it (prefetch).
+type ResourceHint map[string]string
+
+// dependsOnAny reports whether any resource the named one depends on, directly or not, satisfies pred.
+func (tree *ConfigTree) dependsOnAny(name string, pred func(string, Resource) bool) bool {
+	seen := map[string]bool{name: true}
+	queue := tree.Resources[name].DependsOn
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+		if seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		res, ok := tree.Resources[dep]
+		if !ok {
+			continue
+		}
+		if pred(dep, res) {
+			return true
+		}
+		queue = append(queue, res.DependsOn...)
+	}
+	return false
+}
+
+// dependedUpon reports whether any other resource names this one in its depends_on.
+func (tree *ConfigTree) dependedUpon(name string) bool {
+	for _, res := range tree.Resources {
+		for _, dep := range res.DependsOn {
+			if dep == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inBody tells whether a resource goes at the end of the body, either because it asks to or because something it
+// depends on does and must come first.
+func (tree *ConfigTree) inBody(name string) bool {
+	placedInBody := func(_ string, res Resource) bool {
+		return res.Placement == "body"
+	}
+	return placedInBody(name, tree.Resources[name]) || tree.dependsOnAny(name, placedInBody)
+}
+
+// scriptLoading decides whether a script is loaded with async, defer, or neither. Async scripts run in whatever order
+// they arrive, so a script that depends on others or that others depend on is deferred instead, which keeps the order.
+// A script waiting on a deferred one must be deferred as well.
+func (tree *ConfigTree) scriptLoading(name string, res Resource) string {
+	if res.Async && len(res.DependsOn) == 0 && !tree.dependedUpon(name) {
+		return "async"
+	}
+	deferred := func(_ string, dep Resource) bool {
+		return dep.Defer || dep.Async || dep.Module
+	}
+	if res.Defer || res.Async || (!res.Module && tree.dependsOnAny(name, deferred)) {
+		return "defer"
+	}
+	return ""
+}
+
+// mustFingerprint tells whether a raw or local script that would be placed inline must be deferred to keep the order
+// given by depends_on. Browsers ignore defer on inline scripts, so such a script is written to a fingerprinted file,
+// which can be deferred like any other.
+func (tree *ConfigTree) mustFingerprint(name string, res Resource) bool {
+	inline := res.Method != "url" && !res.Fingerprint
+	return res.Type == "script" && inline && !res.Module && tree.scriptLoading(name, res) == "defer"
+}
+
+// checkLoading warns about loading options that had to be changed to keep the order given by depends_on.
+func (tree *ConfigTree) checkLoading(name string, res Resource) {
+	if res.Type == "script" && res.Async && tree.scriptLoading(name, res) != "async" {
+		log.Printf("WARN: the script %s is deferred rather than async to keep the order given by depends_on", name)
+	}
+	if res.Placement != "body" && tree.inBody(name) {
+		log.Printf("WARN: the resource %s is placed at the end of the body, after the resources it depends on", name)
+	}
+	if tree.mustFingerprint(name, res) {
+		log.Printf("WARN: the inline script %s is written to a fingerprinted file so that it can be deferred", name)
+	}
+}
+
+// loadingAttributes adds to the attributes of a resource those that control how it is loaded.
+func (tree *ConfigTree) loadingAttributes(name string, res Resource, value interface{}) interface{} {
+	attr := make(map[string]string, len(res.Attributes)+4)
+	for key, val := range res.Attributes {
+		attr[key] = val
+	}
+	if res.Type == "script" {
+		if res.Module {
+			attr["type"] = "module"
+		}
+		if res.NoModule {
+			attr["nomodule"] = ""
+		}
+	}
+	switch v := value.(type) {
+	case URLResource:
+		if res.Type == "script" {
+			if loading := tree.scriptLoading(name, res); loading != "" {
+				attr[loading] = ""
+			}
+		}
+		if integrity := tree.integrity(name, res); integrity != "" {
+			attr["integrity"] = integrity
+			if _, ok := attr["crossorigin"]; !ok {
+				attr["crossorigin"] = "anonymous"
+			}
+		}
+		v.Attributes = attr
+		return v
+	case RawResource:
+		v.Attributes = attr
+		return v
+	}
+	return value
+}
+
+// resourceHint gives the preload or prefetch link for a resource loaded from a URL, if it asks for one.
+func resourceHint(res Resource, value interface{}) (ResourceHint, bool) {
+	v, ok := value.(URLResource)
+	if !ok || !(res.Preload || res.Prefetch) {
+		return nil, false
+	}
+	hint := ResourceHint{"href": v.String}
+	switch {
+	case res.Prefetch:
+		hint["rel"] = "prefetch"
+	case res.Type == "script" && res.Module:
+		hint["rel"] = "modulepreload"
+	default:
+		hint["rel"] = "preload"
+		hint["as"] = res.Type
+	}
+	// Without the same integrity and CORS mode, the browser cannot reuse what it preloaded.
+	for _, key := range []string{"integrity", "crossorigin"} {
+		if val, ok := v.Attributes[key]; ok {
+			hint[key] = val
+		}
+	}
+	return hint, true
+}
+
+// integrity gives the Subresource Integrity hash of a resource: the one it was given, that of its fingerprinted file,
+// or that of a pinned url resource.
+func (tree *ConfigTree) integrity(name string, res Resource) string {
+	if res.Integrity != "" {
+		return res.Integrity
+	}
+	tree.assetLock.Lock()
+	defer tree.assetLock.Unlock()
+	if a, ok := tree.assets[name]; ok && a.URL != "" {
+		return a.Integrity
+	}
+	if res.Method == "url" && res.Pin {
+		return tree.pinned[res.Value]
+	}
+	return ""
+}
+
+// pinResource fetches a url resource to record the integrity hash of what it serves now. Should the resource change
+// later, browsers will refuse it rather than run something other than what was pinned.
+func (tree *ConfigTree) pinResource(url string) (string, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return integrityHash(content), nil
+}
+
+// pinResources pins the url resources that have not been pinned before. Hashes are kept in pinFile, so that a
+// resource is only trusted the first time it is fetched rather than each time WyWeb starts. This runs in the
+// background, and pages are given the hashes as they become known.
+func (tree *ConfigTree) pinResources(urls []string) {
+	tree.loadPins()
+	fetched := false
+	for _, url := range urls {
+		tree.assetLock.Lock()
+		_, known := tree.pinned[url]
+		tree.assetLock.Unlock()
+		if known {
+			continue
+		}
+		integrity, err := tree.pinResource(url)
+		if err != nil {
+			log.Printf("WARN: could not pin %s: %s", url, err.Error())
+			continue
+		}
+		log.Printf("Pinned %s as %s. Give the resource this integrity to keep it.", url, integrity)
+		tree.assetLock.Lock()
+		tree.pinned[url] = integrity
+		tree.assetLock.Unlock()
+		fetched = true
+	}
+	if fetched {
+		tree.savePins()
+	}
+}
+
+// loadPins reads the hashes of the resources pinned before.
+func (tree *ConfigTree) loadPins() {
+	data, err := os.ReadFile(filepath.Join(tree.rootDir, pinFile))
+	if err != nil {
+		return
+	}
+	pins := make(map[string]string)
+	if err := yaml.Unmarshal(data, &pins); err != nil {
+		log.Printf("WARN: could not read %s: %s", pinFile, err.Error())
+		return
+	}
+	tree.assetLock.Lock()
+	maps.Copy(tree.pinned, pins)
+	tree.assetLock.Unlock()
+}
+
+func (tree *ConfigTree) savePins() {
+	tree.assetLock.Lock()
+	data, err := yaml.Marshal(tree.pinned)
+	tree.assetLock.Unlock()
+	if err == nil {
+		err = os.WriteFile(filepath.Join(tree.rootDir, pinFile), data, 0644)
+	}
+	if err != nil {
+		log.Printf("WARN: could not save the pinned resources to %s: %s", pinFile, err.Error())
+	}
+}
@@ -0,0 +1,196 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Crawlers that gather content for training or answering with AI models rather than for search. These are blocked
+// from the whole site when block_ai is set at the root.
+var aiCrawlers = []string{
+	"GPTBot",
+	"ChatGPT-User",
+	"OAI-SearchBot",
+	"ClaudeBot",
+	"Claude-Web",
+	"anthropic-ai",
+	"Google-Extended",
+	"Applebot-Extended",
+	"CCBot",
+	"PerplexityBot",
+	"Bytespider",
+	"Amazonbot",
+	"meta-externalagent",
+	"FacebookBot",
+	"cohere-ai",
+	"Diffbot",
+	"Omgilibot",
+	"ImagesiftBot",
+}
+
+// fill in whatever is unset from the parent's directives.
+func (d *WWRobotDirectives) inherit(parent WWRobotDirectives) {
+	if d.Index == nil {
+		d.Index = parent.Index
+	}
+	if d.Follow == nil {
+		d.Follow = parent.Follow
+	}
+}
+
+func (r *WWRobots) inherit(parent WWRobots) {
+	r.WWRobotDirectives.inherit(parent.WWRobotDirectives)
+	if len(parent.Crawlers) == 0 {
+		return
+	}
+	// Copy rather than share the parent's map, since the child may add crawlers of its own.
+	crawlers := make(map[string]WWRobotDirectives, len(parent.Crawlers))
+	for name, directives := range parent.Crawlers {
+		crawlers[name] = directives
+	}
+	for name, directives := range r.Crawlers {
+		directives.inherit(crawlers[name])
+		crawlers[name] = directives
+	}
+	r.Crawlers = crawlers
+}
+
+// Indexable reports whether search engines in general may index the page.
+func (d WWRobotDirectives) Indexable() bool {
+	return d.Index == nil || *d.Index
+}
+
+// String gives the directives in the form used by both the robots meta tag and the X-Robots-Tag header, e.g.
+// "noindex, nofollow". Directives that merely grant permission are left out, so the result is empty if there is
+// nothing to forbid.
+func (d WWRobotDirectives) String() string {
+	out := make([]string, 0, 2)
+	if d.Index != nil && !*d.Index {
+		out = append(out, "noindex")
+	}
+	if d.Follow != nil && !*d.Follow {
+		out = append(out, "nofollow")
+	}
+	return strings.Join(out, ", ")
+}
+
+// Directives gives the robots directives of a page keyed by the name of the crawler they apply to, with "robots"
+// meaning all crawlers. Crawlers with nothing to forbid are left out.
+func (r WWRobots) Directives() map[string]string {
+	out := make(map[string]string)
+	if content := r.String(); content != "" {
+		out["robots"] = content
+	}
+	for name, directives := range r.Crawlers {
+		if content := directives.String(); content != "" {
+			out[strings.ToLower(name)] = content
+		}
+	}
+	return out
+}
+
+// HeaderValues gives the values of the X-Robots-Tag header, one per crawler.
+func (r WWRobots) HeaderValues() []string {
+	directives := r.Directives()
+	names := make([]string, 0, len(directives))
+	for name := range directives {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == "robots" {
+			out = append(out, directives[name])
+		} else {
+			out = append(out, name+": "+directives[name])
+		}
+	}
+	return out
+}
+
+// hide keeps a draft or protected page from being indexed or followed. Listing such pages in robots.txt would tell
+// anyone where to find them, so they are kept from search engines by their directives instead.
+func (r *WWRobots) hide() {
+	no := false
+	r.Index = &no
+	r.Follow = &no
+}
+
+func writeRobotsGroup(buf *bytes.Buffer, agents []string, allow []string, disallow []string) {
+	for _, agent := range agents {
+		fmt.Fprintf(buf, "User-agent: %s\n", agent)
+	}
+	for _, path := range allow {
+		fmt.Fprintf(buf, "Allow: %s\n", path)
+	}
+	for _, path := range disallow {
+		fmt.Fprintf(buf, "Disallow: %s\n", path)
+	}
+	// An empty Disallow grants access to everything, which a group must say explicitly if it has no other rules.
+	if len(allow) == 0 && len(disallow) == 0 {
+		buf.WriteString("Disallow:\n")
+	}
+	buf.WriteString("\n")
+}
+
+// RobotsTxt generates robots.txt from the rules given at the root.
+func (tree *ConfigTree) RobotsTxt() []byte {
+	tree.RLock()
+	defer tree.RUnlock()
+	var buf bytes.Buffer
+	robots := tree.Root.Robots
+	everyone := false
+	for _, rule := range robots.Rules {
+		if len(rule.UserAgent) == 0 {
+			continue
+		}
+		everyone = everyone || slices.Contains(rule.UserAgent, "*")
+		writeRobotsGroup(&buf, rule.UserAgent, rule.Allow, rule.Disallow)
+	}
+	if !everyone {
+		writeRobotsGroup(&buf, []string{"*"}, nil, nil)
+	}
+	if robots.BlockAI {
+		writeRobotsGroup(&buf, aiCrawlers, nil, []string{"/"})
+	}
+	fmt.Fprintf(&buf, "Sitemap: https://%s/sitemap.xml\n", tree.Domain)
+	for _, lang := range tree.Languages[min(1, len(tree.Languages)):] {
+		fmt.Fprintf(&buf, "Sitemap: https://%s/%s\n", tree.Domain, sitemapName(lang, ""))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,190 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+)
+
+// Parts with an explicit part number come first, in order. Any remaining parts are ordered by date.
+func sortSeries(parts []*ConfigNode) {
+	sort.SliceStable(parts, func(i, j int) bool {
+		a, b := parts[i].Series.Part, parts[j].Series.Part
+		switch {
+		case a != 0 && b != 0 && a != b:
+			return a < b
+		case a != 0 && b == 0:
+			return true
+		case a == 0 && b != 0:
+			return false
+		}
+		return parts[i].Date.Before(parts[j].Date)
+	})
+}
+
+func seriesPath(name string) string {
+	return "/series/" + url.PathEscape(name)
+}
+
+func (node *ConfigNode) registerSeries() {
+	if !node.isArticle() || node.Series.IsZero() || node.Draft {
+		return
+	}
+	tree := node.Tree
+	name := node.Series.Name
+	tree.Lock()
+	parts := slices.DeleteFunc(slices.Clone(tree.SeriesDB[name]), func(n *ConfigNode) bool {
+		return n.Path == node.Path
+	})
+	parts = append(parts, node)
+	sortSeries(parts)
+	tree.SeriesDB[name] = parts
+	tree.Unlock()
+	setSeriesNavLinks(parts)
+}
+
+func (node *ConfigNode) unregisterSeries() {
+	if node == nil || node.Series.IsZero() {
+		return
+	}
+	tree := node.Tree
+	name := node.Series.Name
+	tree.Lock()
+	parts := slices.DeleteFunc(slices.Clone(tree.SeriesDB[name]), func(n *ConfigNode) bool {
+		return n == node
+	})
+	if len(parts) == 0 {
+		delete(tree.SeriesDB, name)
+		tree.Unlock()
+		return
+	}
+	tree.SeriesDB[name] = parts
+	tree.Unlock()
+	setSeriesNavLinks(parts)
+}
+
+// Series navlinks are kept separate from Prev and Next, which always follow the date order of the parent listing. Every
+// part is built again on its next request, so that its series box lists the parts as they are now.
+func setSeriesNavLinks(parts []*ConfigNode) {
+	for i, part := range parts {
+		if i > 0 {
+			setNavLink(&part.SeriesPrev, "/"+parts[i-1].Path, parts[i-1].Title)
+		} else {
+			setNavLink(&part.SeriesPrev, "", "")
+		}
+		if i < len(parts)-1 {
+			setNavLink(&part.SeriesNext, "/"+parts[i+1].Path, parts[i+1].Title)
+		} else {
+			setNavLink(&part.SeriesNext, "", "")
+		}
+		part.invalidate()
+	}
+}
+
+func (tree *ConfigTree) GetSeries(name string) []*ConfigNode {
+	tree.RLock()
+	defer tree.RUnlock()
+	return tree.SeriesDB[name]
+}
+
+func (tree *ConfigTree) GetSeriesNames() []string {
+	tree.RLock()
+	defer tree.RUnlock()
+	names := make([]string, 0, len(tree.SeriesDB))
+	for name := range tree.SeriesDB {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func BuildSeriesBox(node *ConfigNode) *HTMLElement {
+	parts := node.Tree.GetSeries(node.Series.Name)
+	box := NewHTMLElement("aside", Class("series-box"), AriaLabel(node.T("Series")))
+	position := slices.Index(parts, node.original()) + 1
+	heading := box.AppendNew("div", Class("series-title"))
+	heading.AppendText(node.T("Part %d of %d in the series", position, len(parts)))
+	heading.AppendNew("a", Href(seriesPath(node.Series.Name))).AppendText(node.Series.Name)
+	ol := box.AppendNew("ol", Class("series-parts"))
+	for _, part := range parts {
+		if part == node.original() {
+			ol.AppendNew("li", Class("series-part series-current"), map[string]string{"aria-current": "page"}).AppendText(node.Title)
+			continue
+		}
+		ol.AppendNew("li", Class("series-part")).AppendNew("a", Href("/"+part.Path)).AppendText(part.titleIn(node.Lang))
+	}
+	navlinks := box.AppendNew("nav", Class("series-navlinks"))
+	navlinks.AppendNew("div",
+		Class("navlink series-navlink-prev"),
+	).AppendNew("a",
+		Href(node.SeriesPrev.Path),
+	).AppendText(node.SeriesPrev.Text)
+	navlinks.AppendNew("div",
+		Class("navlink series-navlink-next"),
+	).AppendNew("a",
+		Href(node.SeriesNext.Path),
+	).AppendText(node.SeriesNext.Text)
+	return box
+}
+
+// BuildSeriesListing lists every part of the named series in order, in the language of node, which is the root or a
+// translation of it. If name is empty, an index of all series is built instead.
+func BuildSeriesListing(node *ConfigNode, name string, crumbs *HTMLElement) (*HTMLElement, error) {
+	tree := node.Tree
+	if name == "" {
+		body := NewHTMLElement("body")
+		header := body.AppendNew("header", Class("listing-header"))
+		header.Append(crumbs)
+		header.AppendNew("h1").AppendText(node.T("Series"))
+		ul := body.AppendNew("article").AppendNew("ul", Class("series-index"))
+		for _, series := range tree.GetSeriesNames() {
+			li := ul.AppendNew("li")
+			li.AppendNew("a", Href(seriesPath(series))).AppendText(series)
+			li.AppendNew("span", Class("series-count")).AppendText(node.T("%d parts", len(tree.GetSeries(series))))
+		}
+		return body, nil
+	}
+	parts := tree.GetSeries(name)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no series named %s", name)
+	}
+	items := make([]Listable, len(parts))
+	for i, part := range parts {
+		items[i] = part
+	}
+	return BuildListing(node, items, crumbs, name, node.T("A series in %d parts", len(parts))), nil
+}
@@ -0,0 +1,273 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+func (tree *ConfigTree) baseURL() string {
+	return "https://" + tree.Domain + "/"
+}
+
+func (node *ConfigNode) pageURL() string {
+	return node.Tree.baseURL() + node.Path + node.langQuery()
+}
+
+// Other objects refer to the site and its publisher by these ids rather than repeating them in full.
+func (tree *ConfigTree) websiteID() string {
+	return tree.baseURL() + "#website"
+}
+
+func (tree *ConfigTree) publisherID() string {
+	return tree.baseURL() + "#publisher"
+}
+
+func marshalStructuredData(data map[string]interface{}) string {
+	jsonld, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return ""
+	}
+	return string(jsonld)
+}
+
+// publisherData describes the publisher given in the root wyweb file. If there is none, the site's author is taken to
+// be its publisher.
+func (tree *ConfigTree) publisherData() map[string]interface{} {
+	publisher := tree.Publisher
+	if publisher.Name == "" {
+		publisher.Name = tree.Root.Author
+	}
+	kind := "Organization"
+	if strings.EqualFold(publisher.Type, "person") || (publisher.Type == "" && tree.Publisher.Name == "") {
+		kind = "Person"
+	}
+	out := map[string]interface{}{
+		"@type": kind,
+		"@id":   tree.publisherID(),
+		"name":  publisher.Name,
+	}
+	if publisher.URL != "" {
+		out["url"] = publisher.URL
+	} else {
+		out["url"] = tree.baseURL()
+	}
+	if publisher.Logo != "" {
+		logo := map[string]interface{}{
+			"@type": "ImageObject",
+			"url":   resolveImageURL(tree.Root, publisher.Logo),
+		}
+		// A person has an image rather than a logo.
+		if kind == "Person" {
+			out["image"] = logo
+		} else {
+			out["logo"] = logo
+		}
+	}
+	if len(publisher.SameAs) > 0 {
+		out["sameAs"] = publisher.SameAs
+	}
+	return out
+}
+
+// WebSiteData describes the site as a whole, including the tag search as a SearchAction.
+func (tree *ConfigTree) WebSiteData() string {
+	data := map[string]interface{}{
+		"@context":  "https://schema.org",
+		"@type":     "WebSite",
+		"@id":       tree.websiteID(),
+		"url":       tree.baseURL(),
+		"name":      tree.Root.Title,
+		"publisher": tree.publisherData(),
+		"potentialAction": map[string]interface{}{
+			"@type": "SearchAction",
+			"target": map[string]interface{}{
+				"@type":       "EntryPoint",
+				"urlTemplate": tree.baseURL() + "tags?tags={search_term_string}",
+			},
+			"query-input": "required name=search_term_string",
+		},
+	}
+	if tree.Root.Description != "" {
+		data["description"] = tree.Root.Description
+	}
+	return marshalStructuredData(data)
+}
+
+// DocumentStructuredData gives all of the JSON-LD belonging in the head of a page, including that of the site itself.
+func (node *ConfigNode) DocumentStructuredData() []string {
+	out := make([]string, 0, len(node.StructuredData)+1)
+	out = append(out, node.StructuredData...)
+	return append(out, node.Tree.WebSiteData())
+}
+
+func personData(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"@type": "Person",
+		"name":  name,
+	}
+}
+
+// countWords counts the words of prose in a document, leaving out code.
+func countWords(doc ast.Node, source []byte) int {
+	count := 0
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.CodeSpan:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			count += len(strings.FieldsFunc(string(t.Segment.Value(source)), func(r rune) bool {
+				return unicode.IsSpace(r) || unicode.IsPunct(r) && r != '\'' && r != '-'
+			}))
+		}
+		return ast.WalkContinue, nil
+	})
+	return count
+}
+
+// postStructuredData describes a post as a BlogPosting.
+func (node *ConfigNode) postStructuredData() string {
+	url := node.pageURL()
+	data := map[string]interface{}{
+		"@context":      "https://schema.org",
+		"@type":         "BlogPosting",
+		"headline":      node.Title,
+		"url":           url,
+		"datePublished": node.Date.Format(time.RFC3339),
+		"dateModified":  node.Updated.Format(time.RFC3339),
+		"mainEntityOfPage": map[string]interface{}{
+			"@type": "WebPage",
+			"@id":   url,
+		},
+		"isPartOf":  map[string]interface{}{"@id": node.Tree.websiteID()},
+		"publisher": node.Tree.publisherData(),
+	}
+	if node.Author != "" {
+		data["author"] = personData(node.Author)
+	}
+	if node.Description != "" {
+		data["description"] = node.Description
+	}
+	if img := node.socialImage(); img.URL != "" {
+		data["image"] = img.URL
+	}
+	if len(node.Tags) > 0 {
+		data["keywords"] = strings.Join(node.Tags, ", ")
+	}
+	if doc, source, err := node.parsedDocument(); err == nil {
+		data["wordCount"] = countWords(doc, source)
+	}
+	return marshalStructuredData(data)
+}
+
+// pageStructuredData describes a plain page as a WebPage.
+func (node *ConfigNode) pageStructuredData() string {
+	data := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "WebPage",
+		"@id":      node.pageURL(),
+		"url":      node.pageURL(),
+		"name":     node.Title,
+		"isPartOf": map[string]interface{}{"@id": node.Tree.websiteID()},
+	}
+	if node.Description != "" {
+		data["description"] = node.Description
+	}
+	if !node.Updated.IsZero() {
+		data["dateModified"] = node.Updated.Format(time.RFC3339)
+	}
+	return marshalStructuredData(data)
+}
+
+// listingStructuredData describes a listing as a CollectionPage whose main entity is the list of its items.
+func (node *ConfigNode) listingStructuredData(items []Listable) string {
+	elements := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		child, ok := item.(*ConfigNode)
+		if !ok {
+			continue
+		}
+		elements = append(elements, map[string]interface{}{
+			"@type":    "ListItem",
+			"position": len(elements) + 1,
+			"url":      child.pageURL(),
+			"name":     child.Title,
+		})
+	}
+	data := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "CollectionPage",
+		"@id":      node.pageURL(),
+		"url":      node.pageURL(),
+		"name":     node.Title,
+		"isPartOf": map[string]interface{}{"@id": node.Tree.websiteID()},
+		"mainEntity": map[string]interface{}{
+			"@type":           "ItemList",
+			"numberOfItems":   len(elements),
+			"itemListElement": elements,
+		},
+	}
+	if node.Description != "" {
+		data["description"] = node.Description
+	}
+	return marshalStructuredData(data)
+}
+
+// galleryStructuredData describes a gallery as an ImageGallery made up of the given images.
+func (node *ConfigNode) galleryStructuredData(images []interface{}) string {
+	data := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "ImageGallery",
+		"@id":      node.pageURL(),
+		"url":      node.pageURL(),
+		"name":     node.Title,
+		"isPartOf": map[string]interface{}{"@id": node.Tree.websiteID()},
+		"image":    images,
+	}
+	if node.Description != "" {
+		data["description"] = node.Description
+	}
+	if node.Author != "" {
+		data["author"] = personData(node.Author)
+	}
+	return marshalStructuredData(data)
+}
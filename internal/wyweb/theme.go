@@ -0,0 +1,377 @@
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//                                                                                               //
+//                                                                                               //
+//         oooooo   oooooo     oooo           oooooo   oooooo     oooo         .o8               //
+//          `888.    `888.     .8'             `888.    `888.     .8'         "888               //
+//           `888.   .8888.   .8' oooo    ooo   `888.   .8888.   .8' .ooooo.   888oooo.          //
+//            `888  .8'`888. .8'   `88.  .8'     `888  .8'`888. .8' d88' `88b  d88' `88b         //
+//             `888.8'  `888.8'     `88..8'       `888.8'  `888.8'  888ooo888  888   888         //
+//              `888'    `888'       `888'         `888'    `888'   888    .o  888   888         //
+//               `8'      `8'         .8'           `8'      `8'    `Y8bod8P'  `Y8bod8P'         //
+//                                .o..P'                                                         //
+//                                `Y8P'                                                          //
+//                                                                                               //
+//                                                                                               //
+//                              Copyright (C) 2024  Wyatt Sheffield                              //
+//                                                                                               //
+//                 This program is free software: you can redistribute it and/or                 //
+//                 modify it under the terms of the GNU General Public License as                //
+//                 published by the Free Software Foundation, either version 3 of                //
+//                      the License, or (at your option) any later version.                      //
+//                                                                                               //
+//                This program is distributed in the hope that it will be useful,                //
+//                 but WITHOUT ANY WARRANTY; without even the implied warranty of                //
+//                 MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the                 //
+//                          GNU General Public License for more details.                         //
+//                                                                                               //
+//                   You should have received a copy of the GNU General Public                   //
+//                         License along with this program.  If not, see                         //
+//                                <https://www.gnu.org/licenses/>.                               //
+//                                                                                               //
+//                                                                                               //
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+package wyweb
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A theme is a directory of html/template files that take the place of the markup WyWeb would otherwise generate.
+// Each file is named after the part of the page it renders: base.html, post.html, page.html, listing.html,
+// gallery.html, list-item.html, footer.html, or error.html. A variant picked with the layout setting is named with
+// the layout before the extension, e.g. post.wide.html. Anything the theme does not provide is built as usual.
+type Theme struct {
+	sync.RWMutex
+	Dir       string
+	templates *template.Template
+	loaded    time.Time
+}
+
+// The data given to a theme's templates. Markup that WyWeb generates, such as the breadcrumbs or the rendered
+// markdown, is given ready to be placed wherever the template sees fit.
+type TemplateData struct {
+	PageData
+	Kind        string
+	Layout      string
+	Lang        string
+	Site        string
+	Now         time.Time
+	Menu        template.HTML
+	Breadcrumbs template.HTML
+	TOC         template.HTML
+	Nav         template.HTML
+	Body        template.HTML
+	Related     template.HTML
+	Tags        []string
+	Items       []template.HTML
+	Images      []TemplateImage
+	Status      int
+	Head        template.HTML
+}
+
+// A single image of a gallery, as given to the gallery template.
+type TemplateImage struct {
+	ID        string
+	Num       int
+	Thumbnail string
+	Full      string
+	Alt       string
+	Title     string
+}
+
+func LoadTheme(dir string) (*Theme, error) {
+	theme := &Theme{Dir: dir}
+	err := theme.parse()
+	if err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
+
+func (t *Theme) parse() error {
+	templates, err := template.ParseGlob(filepath.Join(t.Dir, "*.html"))
+	if err != nil {
+		return err
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.templates = templates
+	t.loaded = time.Now()
+	return nil
+}
+
+// changed reports whether any file of the theme has been modified since it was loaded.
+func (t *Theme) changed() bool {
+	if t == nil {
+		return false
+	}
+	t.RLock()
+	loaded := t.loaded
+	t.RUnlock()
+	entries, err := os.ReadDir(t.Dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && info.ModTime().After(loaded) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Theme) lookup(name string, layout string) *template.Template {
+	if t == nil {
+		return nil
+	}
+	t.RLock()
+	defer t.RUnlock()
+	if layout != "" {
+		if tmpl := t.templates.Lookup(name + "." + layout + ".html"); tmpl != nil {
+			return tmpl
+		}
+	}
+	return t.templates.Lookup(name + ".html")
+}
+
+// Has reports whether the theme provides the named template, in which case WyWeb's own markup is not used.
+func (t *Theme) Has(name string, layout string) bool {
+	return t.lookup(name, layout) != nil
+}
+
+// Execute renders the named template. ok is false if the theme has no such template or it failed, in which case the
+// caller should fall back to building the markup itself.
+func (t *Theme) Execute(name string, layout string, data *TemplateData) (string, bool) {
+	tmpl := t.lookup(name, layout)
+	if tmpl == nil {
+		return "", false
+	}
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		log.Printf("WARN: theme template %s: %s", tmpl.Name(), err.Error())
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// reloadTheme parses the theme again if it has changed, and discards every page built with the old templates.
+func (tree *ConfigTree) reloadTheme() {
+	if !tree.Theme.changed() {
+		return
+	}
+	err := tree.Theme.parse()
+	if err != nil {
+		log.Printf("WARN: could not reload theme: %s", err.Error())
+		return
+	}
+	var dft func(*ConfigNode)
+	dft = func(node *ConfigNode) {
+		node.invalidate()
+		for _, child := range node.Children {
+			dft(child)
+		}
+	}
+	dft(tree.Root)
+}
+
+// invalidate discards the rendered page so that it is built again on the next request.
+func (node *ConfigNode) invalidate() {
+	node.HTML = nil
+	node.StructuredData = make([]string, 0)
+	node.forgetVariants()
+}
+
+// templateData gathers what every template is given about a page.
+func (node *ConfigNode) templateData() *TemplateData {
+	data := &TemplateData{
+		PageData: node.PageData,
+		Kind:     KindNames[node.NodeKind],
+		Layout:   node.Layout,
+		Lang:     node.Language(),
+		Now:      time.Now(),
+	}
+	if node.Tree != nil && node.Tree.Root != nil {
+		data.Site = node.Tree.Root.Title
+	}
+	return data
+}
+
+// renderedHTML renders an element for inclusion in a template.
+func renderedHTML(elem *HTMLElement) template.HTML {
+	if elem == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	RenderHTML(elem, &buf)
+	return template.HTML(buf.String())
+}
+
+// rawHTML wraps markup rendered by a template so that it can take the place of a generated element.
+func rawHTML(text string) *HTMLElement {
+	elem := NewHTMLElement("")
+	elem.Content = strings.TrimRight(text, "\n")
+	elem.NoIndent()
+	return elem
+}
+
+// themedBody makes the body of a page from the output of a template.
+func themedBody(text string, attr ...map[string]string) *HTMLElement {
+	body := NewHTMLElement("body", attr...)
+	body.Append(rawHTML(text))
+	return body
+}
+
+// themedPost renders a post or page from the theme. The series box, episode player, and recipe card are given as part
+// of the body, in the same places WyWeb would put them.
+func (node *ConfigNode) themedPost(name string, content string, TOC, crumbs *HTMLElement) (*HTMLElement, bool) {
+	theme := node.Tree.Theme
+	if !theme.Has(name, node.Layout) {
+		return nil, false
+	}
+	data := node.templateData()
+	data.Breadcrumbs = renderedHTML(crumbs)
+	data.TOC = renderedHTML(TOC)
+	var body strings.Builder
+	if name == "post" {
+		data.Nav = renderedHTML(BuildNavlinks(node))
+		data.Tags = node.Tags
+		if !node.Series.IsZero() {
+			body.WriteString(string(renderedHTML(BuildSeriesBox(node))))
+		}
+		if node.Episode != nil {
+			body.WriteString(string(renderedHTML(BuildEpisodePlayer(node))))
+		}
+		if !node.Related.IsZero() {
+			data.Related = renderedHTML(BuildRelated(node))
+		}
+	}
+	body.WriteString(content)
+	if node.Recipe != nil {
+		// The markers let RescaledRecipe find the card again within the rendered template.
+		body.WriteString(recipeCardStart)
+		body.WriteString(string(renderedHTML(BuildRecipeCard(node, 0, ""))))
+		body.WriteString(recipeCardEnd)
+	}
+	data.Body = template.HTML(body.String())
+	text, ok := theme.Execute(name, node.Layout, data)
+	if !ok {
+		return nil, false
+	}
+	return themedBody(text), true
+}
+
+// themedListing renders a listing of items from the theme, each item having been rendered by BuildListItem.
+func (node *ConfigNode) themedListing(items []Listable, crumbs *HTMLElement, title, description string) (*HTMLElement, bool) {
+	theme := node.Tree.Theme
+	if !theme.Has("listing", node.Layout) {
+		return nil, false
+	}
+	data := node.templateData()
+	data.Title = title
+	data.Description = description
+	data.Breadcrumbs = renderedHTML(crumbs)
+	data.Items = make([]template.HTML, 0, len(items))
+	for _, item := range items {
+		if elem := BuildListItem(item); elem != nil {
+			data.Items = append(data.Items, renderedHTML(elem))
+		}
+	}
+	text, ok := theme.Execute("listing", node.Layout, data)
+	if !ok {
+		return nil, false
+	}
+	return themedBody(text), true
+}
+
+// themedListItem renders a post in a listing from the theme. The layout of the listing it is in picks the variant.
+func (node *ConfigNode) themedListItem() (*HTMLElement, bool) {
+	layout := ""
+	if node.Parent != nil {
+		layout = node.Parent.Layout
+	}
+	data := node.templateData()
+	data.Body = template.HTML(node.Preview)
+	data.Tags = node.Tags
+	text, ok := node.Tree.Theme.Execute("list-item", layout, data)
+	if !ok {
+		return nil, false
+	}
+	return rawHTML(text), true
+}
+
+// themedGallery renders a gallery from the theme, which is given every image along with its thumbnail.
+func (node *ConfigNode) themedGallery(pairs []imgPair, richImages map[string]RichImage, crumbs *HTMLElement) (*HTMLElement, bool) {
+	theme := node.Tree.Theme
+	if !theme.Has("gallery", node.Layout) {
+		return nil, false
+	}
+	data := node.templateData()
+	data.Breadcrumbs = renderedHTML(crumbs)
+	data.Images = make([]TemplateImage, 0, len(pairs))
+	for idx, pair := range pairs {
+		image := TemplateImage{Num: idx, Thumbnail: pair.Thumb, Full: pair.Full}
+		if img, ok := richImages[filepath.Base(pair.Full)]; ok {
+			image.ID = img.GetIDb64()
+			image.Alt = img.Alt
+			image.Title = img.Title
+		}
+		data.Images = append(data.Images, image)
+	}
+	text, ok := theme.Execute("gallery", node.Layout, data)
+	if !ok {
+		return nil, false
+	}
+	return themedBody(text, Class("gallery-page")), true
+}
+
+// themedFooter renders the footer of a page from the theme.
+func (node *ConfigNode) themedFooter() (*HTMLElement, bool) {
+	text, ok := node.Tree.Theme.Execute("footer", node.Layout, node.templateData())
+	if !ok {
+		return nil, false
+	}
+	return rawHTML(text), true
+}
+
+// ErrorPage renders the page shown for the given HTTP status from the theme. ok is false if the theme has no error
+// template.
+func (tree *ConfigTree) ErrorPage(status int) ([]byte, bool) {
+	data := tree.Root.templateData()
+	data.Status = status
+	data.Title = http.StatusText(status)
+	data.Menu = renderedHTML(tree.Root.BuildMenu())
+	text, ok := tree.Theme.Execute("error", "", data)
+	return []byte(text), ok
+}
+
+// RenderDocument puts together the complete document of a page, using the theme's base template if there is one. The
+// site menu is put at the top of the body, except that a base template is given it as .Menu to place itself.
+func (node *ConfigNode) RenderDocument(bodyHTML *HTMLElement, headData HTMLHeadData, structuredData ...string) (bytes.Buffer, error) {
+	menu := node.BuildMenu()
+	if !node.Tree.Theme.Has("base", node.Layout) {
+		return BuildDocument(withMenu(bodyHTML, menu), headData, structuredData...)
+	}
+	data := node.templateData()
+	data.Title = headData.Title
+	data.Menu = renderedHTML(menu)
+	data.Head = renderedHTML(buildDocumentHead(headData, structuredData...))
+	data.Body = renderedHTML(withBodyResources(bodyHTML, headData))
+	text, ok := node.Tree.Theme.Execute("base", node.Layout, data)
+	if !ok {
+		return BuildDocument(withMenu(bodyHTML, menu), headData, structuredData...)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(text)
+	return buf, nil
+}
@@ -44,6 +44,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -60,6 +62,7 @@ const (
 	WWLISTING
 	WWPODCAST
 	WWRECIPE
+	WWPAGE
 )
 
 var KindNames = map[WWNodeKind]string{
@@ -69,6 +72,7 @@ var KindNames = map[WWNodeKind]string{
 	WWLISTING: "listing",
 	WWPODCAST: "podcast",
 	WWRECIPE:  "recipe",
+	WWPAGE:    "page",
 }
 
 type WWNavLink struct {
@@ -76,30 +80,216 @@ type WWNavLink struct {
 	Text string `yaml:"text,omitempty"`
 }
 
+// An entry of the site menu, linking either to a page of the site by its path from the document root (Node) or to any
+// other URL (Path). Entries naming a page take their text from its title unless given their own.
+type WWMenuEntry struct {
+	Text     string        `yaml:"text,omitempty"`
+	Path     string        `yaml:"path,omitempty"`
+	Node     string        `yaml:"node,omitempty"`
+	Icon     string        `yaml:"icon,omitempty"`
+	Children []WWMenuEntry `yaml:"children,omitempty"`
+}
+
 func (r WWNavLink) IsZero() bool {
 	return r.Path == "" && r.Text == ""
 }
 
+// A series may be given either as a bare name or as a name with a part number.
+type WWSeries struct {
+	Name string `yaml:"name,omitempty"`
+	Part int    `yaml:"part,omitempty"`
+}
+
+func (s WWSeries) IsZero() bool {
+	return s.Name == ""
+}
+
+func (s *WWSeries) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Name = node.Value
+		return nil
+	}
+	type plain WWSeries
+	return node.Decode((*plain)(s))
+}
+
+// Controls the "Related" section at the end of a post. Scope is either "site" or "listing". If content is true, the
+// text of each post is compared in addition to its tags.
+type WWRelated struct {
+	Count   int    `yaml:"count,omitempty"`
+	Scope   string `yaml:"scope,omitempty"`
+	Content bool   `yaml:"content,omitempty"`
+}
+
+func (r WWRelated) IsZero() bool {
+	return r.Count == 0 && r.Scope == "" && !r.Content
+}
+
+// Controls the contents of feeds. If FullContent is true, feed entries carry the fully rendered page rather than just
+// its description.
+type WWFeed struct {
+	FullContent bool `yaml:"full_content,omitempty"`
+}
+
+func (f WWFeed) IsZero() bool {
+	return !f.FullContent
+}
+
+// The footer of a page. Content is markdown, shown above the columns of links and the badges. If YearRange is true, or
+// Since is given, the copyright notice runs from the first year of publication through the current year. Setting
+// footer to false leaves the footer out entirely.
+type WWFooter struct {
+	Hidden    bool             `yaml:"-"`
+	Content   string           `yaml:"content,omitempty"`
+	Columns   []WWFooterColumn `yaml:"columns,omitempty"`
+	YearRange bool             `yaml:"year_range,omitempty"`
+	Since     int              `yaml:"since,omitempty"`
+	Badges    []WWBadge        `yaml:"badges,omitempty"`
+	HideLogo  bool             `yaml:"hide_logo,omitempty"`
+	set       bool
+	dir       string // The page the footer was given on, from which its links and images are relative
+}
+
+type WWFooterColumn struct {
+	Title string      `yaml:"title,omitempty"`
+	Links []WWNavLink `yaml:"links,omitempty"`
+}
+
+// A small image in the footer, such as a license badge, optionally linking elsewhere.
+type WWBadge struct {
+	Image string `yaml:"image,omitempty"`
+	Alt   string `yaml:"alt,omitempty"`
+	Link  string `yaml:"link,omitempty"`
+}
+
+// A footer given in any form, even footer: true, replaces the one that would have been inherited.
+func (f WWFooter) IsZero() bool {
+	return !f.set
+}
+
+func (f *WWFooter) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var show bool
+		if err := node.Decode(&show); err != nil {
+			return err
+		}
+		*f = WWFooter{Hidden: !show, set: true}
+		return nil
+	}
+	type plain WWFooter
+	if err := node.Decode((*plain)(f)); err != nil {
+		return err
+	}
+	f.set = true
+	return nil
+}
+
+// Tells search engines whether a page may be indexed and whether its links may be followed. An unset field is inherited
+// from the parent page; if it is unset everywhere, crawlers are free to do as they please.
+type WWRobotDirectives struct {
+	Index  *bool `yaml:"index,omitempty"`
+	Follow *bool `yaml:"follow,omitempty"`
+}
+
+func (d WWRobotDirectives) IsZero() bool {
+	return d.Index == nil && d.Follow == nil
+}
+
+// A group of rules for robots.txt
+type WWRobotsRule struct {
+	UserAgent []string `yaml:"user_agent,omitempty"`
+	Allow     []string `yaml:"allow,omitempty"`
+	Disallow  []string `yaml:"disallow,omitempty"`
+}
+
+// Controls the robots meta tag and X-Robots-Tag header of a page. Crawlers holds directives for specific crawlers, keyed
+// by the crawler's name (e.g. googlebot). Rules and BlockAI only have meaning at the root, where they are used to
+// generate robots.txt.
+type WWRobots struct {
+	WWRobotDirectives `yaml:",inline"`
+	Crawlers          map[string]WWRobotDirectives `yaml:"crawlers,omitempty"`
+	Rules             []WWRobotsRule               `yaml:"rules,omitempty"`
+	BlockAI           bool                         `yaml:"block_ai,omitempty"`
+}
+
+func (r WWRobots) IsZero() bool {
+	return r.WWRobotDirectives.IsZero() && len(r.Crawlers) == 0 && len(r.Rules) == 0 && !r.BlockAI
+}
+
+// The person or organization responsible for the site, as described in structured data. Type is either "person" or
+// "organization".
+type WWPublisher struct {
+	Type   string   `yaml:"type,omitempty"`
+	Name   string   `yaml:"name,omitempty"`
+	URL    string   `yaml:"url,omitempty"`
+	Logo   string   `yaml:"logo,omitempty"`
+	SameAs []string `yaml:"same_as,omitempty"`
+}
+
 type PageData struct {
-	Author      string    `yaml:"author,omitempty"`
-	Title       string    `yaml:"title,omitempty"`
-	Description string    `yaml:"description,omitempty"`
-	Copyright   string    `yaml:"copyright,omitempty"`
-	Date        time.Time `yaml:"date,omitempty"`
-	Updated     time.Time `yaml:"updated,omitempty"`
-	Path        string    `yaml:"path,omitempty"`
-	ParentPath  string    `yaml:"parent_path,omitempty"`
-	Next        WWNavLink `yaml:"next,omitempty"`
-	Prev        WWNavLink `yaml:"prev,omitempty"`
-	Up          WWNavLink `yaml:"up,omitempty"`
+	Author      string      `yaml:"author,omitempty"`
+	Title       string      `yaml:"title,omitempty"`
+	Description string      `yaml:"description,omitempty"`
+	Image       string      `yaml:"image,omitempty"`
+	Copyright   string      `yaml:"copyright,omitempty"`
+	Date        time.Time   `yaml:"date,omitempty"`
+	Updated     time.Time   `yaml:"updated,omitempty"`
+	Path        string      `yaml:"path,omitempty"`
+	ParentPath  string      `yaml:"parent_path,omitempty"`
+	Next        WWNavLink   `yaml:"next,omitempty"`
+	Prev        WWNavLink   `yaml:"prev,omitempty"`
+	Up          WWNavLink   `yaml:"up,omitempty"`
+	Related     WWRelated   `yaml:"related,omitempty"`
+	Feed        WWFeed      `yaml:"feed,omitempty"`
+	Footer      WWFooter    `yaml:"footer,omitempty"`
+	Draft       bool        `yaml:"draft,omitempty"`
+	Protected   bool        `yaml:"protected,omitempty"`
+	Robots      WWRobots    `yaml:"robots,omitempty"`
+	Highlight   WWHighlight `yaml:"highlight,omitempty"`
+	Layout      string      `yaml:"layout,omitempty"`
+	// Translated fields by language, then by field name, collected from keys such as title.es
+	Translations map[string]map[string]string `yaml:"-"`
 }
 
 type Resource struct {
-	Attributes map[string]string `yaml:"attributes,omitempty"`
-	Type       string            `yaml:"type,omitempty"`
-	Method     string            `yaml:"method,omitempty"`
-	Value      string            `yaml:"value,omitempty"`
-	DependsOn  []string          `yaml:"depends_on,omitempty"`
+	Attributes  map[string]string `yaml:"attributes,omitempty"`
+	Type        string            `yaml:"type,omitempty"`
+	Method      string            `yaml:"method,omitempty"`
+	Value       string            `yaml:"value,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty"`
+	Minify      *bool             `yaml:"minify,omitempty"`
+	Fingerprint bool              `yaml:"fingerprint,omitempty"`
+	Async       bool              `yaml:"async,omitempty"`
+	Defer       bool              `yaml:"defer,omitempty"`
+	Module      bool              `yaml:"module,omitempty"`
+	NoModule    bool              `yaml:"nomodule,omitempty"`
+	Preload     bool              `yaml:"preload,omitempty"`
+	Prefetch    bool              `yaml:"prefetch,omitempty"`
+	Placement   string            `yaml:"placement,omitempty"` // "head" (the default) or "body", meaning the end of it
+	Integrity   string            `yaml:"integrity,omitempty"`
+	Pin         bool              `yaml:"pin,omitempty"`  // Fetch a url resource once to fill in its integrity
+	When        *WWCondition      `yaml:"when,omitempty"` // Place the resource on every page matching this, without being asked
+}
+
+// WWHighlight chooses the chroma styles of highlighted code and whether its lines are numbered. Unset settings are
+// inherited.
+type WWHighlight struct {
+	Light       string `yaml:"light,omitempty"` // The style for screens, or for light ones if Dark is set
+	Dark        string `yaml:"dark,omitempty"`  // The style for screens set to prefer dark colours
+	Print       string `yaml:"print,omitempty"`
+	LineNumbers *bool  `yaml:"line_numbers,omitempty"`
+}
+
+// WWCondition describes the pages a resource is placed on without being included by name. Every condition that is
+// set must hold, and a list holds if any of its entries does.
+type WWCondition struct {
+	Code    bool     `yaml:"code,omitempty"`    // The page has a code block
+	Math    bool     `yaml:"math,omitempty"`    // The page has TeX math, a math code block, or <math>
+	Alert   bool     `yaml:"alert,omitempty"`   // The page has an alert
+	Media   bool     `yaml:"media,omitempty"`   // The page embeds audio or video, or is part of a podcast
+	Gallery bool     `yaml:"gallery,omitempty"` // The page is a gallery
+	Kind    []string `yaml:"kind,omitempty"`    // The kind of the page, such as post or gallery
+	Tag     []string `yaml:"tag,omitempty"`     // A tag of the page
 }
 
 type HeadData struct {
@@ -130,9 +320,14 @@ type WyWebRoot struct {
 		Meta      []string `yaml:"meta,omitempty"`
 		Resources []string `yaml:"resources,omitempty"`
 	} `yaml:"always,omitempty"`
-	Index    string `yaml:"index,omitempty"`
-	HeadData `yaml:",inline"`
-	PageData `yaml:",inline"`
+	Index     string            `yaml:"index,omitempty"`
+	Publisher WWPublisher       `yaml:"publisher,omitempty"`
+	Theme     string            `yaml:"theme,omitempty"`
+	Languages []string          `yaml:"languages,omitempty"`
+	Locales   map[string]Locale `yaml:"locales,omitempty"`
+	Menu      []WWMenuEntry     `yaml:"menu,omitempty"`
+	HeadData  `yaml:",inline"`
+	PageData  `yaml:",inline"`
 }
 
 type WyWebListing struct {
@@ -146,6 +341,7 @@ type WyWebPost struct {
 	Index    string   `yaml:"index,omitempty"`
 	Preview  string   `yaml:"preview,omitempty"`
 	Tags     []string `yaml:"tags,omitempty"`
+	Series   WWSeries `yaml:"series,omitempty"`
 }
 
 type RichImage struct {
@@ -224,9 +420,53 @@ func (n *RichImage) GetIDb64() string {
 func (n *RichImage) AsRSSItem() *HTMLElement {
 	item := NewHTMLElement("item")
 	item.AppendNew("title").AppendText(n.Title)
-	item.AppendNew("link").AppendText("https://" + n.ParentPage.Tree.Domain + "/" + n.ParentPage.Path)
+	item.AppendNew("link").Compact().AppendText("https://" + n.ParentPage.Tree.Domain + "/" + n.ParentPage.Path)
 	item.AppendNew("description").AppendText(html.EscapeString(n.Description))
 	item.AppendNew("pubDate").AppendText(n.Date.Format(time.RFC1123Z))
+	for _, tag := range n.Tags {
+		item.AppendNew("category").AppendText(html.EscapeString(tag))
+	}
+	return item
+}
+
+func (n *RichImage) AsAtomEntry() *HTMLElement {
+	page := n.ParentPage
+	link := "https://" + page.Tree.Domain + "/" + page.Path + "#" + n.GetIDb64()
+	entry := NewHTMLElement("entry")
+	entry.AppendNew("title").AppendText(html.EscapeString(n.Title))
+	entry.AppendNew("id").Compact().AppendText(tagURI(page.Tree.Domain, n.Date, filepath.Join(page.Path, n.Filename)))
+	entry.AppendNew("link", Href(link), map[string]string{"rel": "alternate", "type": "text/html"}).SetSelfClosing(true)
+	entry.AppendNew("published").AppendText(n.Date.Format(time.RFC3339))
+	entry.AppendNew("updated").AppendText(n.Date.Format(time.RFC3339))
+	if n.Artist != "" {
+		entry.AppendNew("author").AppendNew("name").AppendText(html.EscapeString(n.Artist))
+	}
+	entry.AppendNew("summary", map[string]string{"type": "html"}).AppendText(html.EscapeString(n.Description))
+	for _, tag := range n.Tags {
+		entry.AppendNew("category", map[string]string{"term": tag}).SetSelfClosing(true)
+	}
+	return entry
+}
+
+func (n *RichImage) AsJSONFeedItem() map[string]interface{} {
+	page := n.ParentPage
+	imageURL := "https://" + page.Tree.Domain + "/" + filepath.Join(page.Path, n.Filename)
+	content := fmt.Sprintf(`<img src="%s" alt="%s" /><p>%s</p>`,
+		imageURL, html.EscapeString(n.Alt), html.EscapeString(n.Description))
+	item := map[string]interface{}{
+		"id":             tagURI(page.Tree.Domain, n.Date, filepath.Join(page.Path, n.Filename)),
+		"url":            "https://" + page.Tree.Domain + "/" + page.Path + "#" + n.GetIDb64(),
+		"title":          n.Title,
+		"content_html":   content,
+		"image":          imageURL,
+		"date_published": n.Date.Format(time.RFC3339),
+	}
+	if n.Artist != "" {
+		item["authors"] = []map[string]string{{"name": n.Artist}}
+	}
+	if len(n.Tags) > 0 {
+		item["tags"] = n.Tags
+	}
 	return item
 }
 
@@ -239,13 +479,33 @@ func (r *RichImage) StructuredData() interface{} {
 	}
 
 	out := map[string]interface{}{
-		"@context":   "https://schema.org/",
 		"@type":      "ImageObject",
-		"contentURL": contentURL.String(),
-		"creator": map[string]interface{}{
-			"@type": "Person",
-			"name":  r.Artist,
-		},
+		"contentUrl": contentURL.String(),
+		"url":        "https://" + r.ParentPage.Tree.Domain + "/" + r.ParentPage.Path + "?image=" + url.QueryEscape(r.GetIDb64()),
+	}
+	if r.Title != "" {
+		out["name"] = r.Title
+	}
+	if r.Description != "" {
+		out["description"] = r.Description
+	}
+	if r.Alt != "" {
+		out["caption"] = r.Alt
+	}
+	if r.Artist != "" {
+		out["creator"] = personData(r.Artist)
+	}
+	if !r.Date.IsZero() {
+		out["dateCreated"] = r.Date.Format(time.DateOnly)
+	}
+	if r.Location != "" {
+		out["contentLocation"] = map[string]interface{}{
+			"@type": "Place",
+			"name":  r.Location,
+		}
+	}
+	if len(r.Tags) > 0 {
+		out["keywords"] = strings.Join(r.Tags, ", ")
 	}
 	return out
 }
@@ -256,6 +516,127 @@ type WyWebGallery struct {
 	GalleryItems []RichImage `yaml:"galleryitems,omitempty"`
 }
 
+// A chapter of a podcast episode. Start is a timestamp such as 1:02:03 or a number of seconds.
+type WWChapter struct {
+	Start string `yaml:"start,omitempty"`
+	Title string `yaml:"title,omitempty"`
+	URL   string `yaml:"url,omitempty"`
+	Image string `yaml:"image,omitempty"`
+}
+
+type WWPodcastOwner struct {
+	Name  string `yaml:"name,omitempty"`
+	Email string `yaml:"email,omitempty"`
+}
+
+// Settings for a podcast as a whole. Categories are Apple Podcasts categories, with a subcategory given after a slash,
+// e.g. "Technology/Tech News". Type is either "episodic" or "serial".
+type WWPodcast struct {
+	Categories []string       `yaml:"categories,omitempty"`
+	Explicit   bool           `yaml:"explicit,omitempty"`
+	Owner      WWPodcastOwner `yaml:"owner,omitempty"`
+	Language   string         `yaml:"language,omitempty"`
+	Type       string         `yaml:"type,omitempty"`
+	GUID       string         `yaml:"guid,omitempty"`
+	Locked     bool           `yaml:"locked,omitempty"`
+	Complete   bool           `yaml:"complete,omitempty"`
+}
+
+// Settings for a single episode, given in the front matter of its show notes. Type is "full", "trailer", or "bonus".
+// If Duration is not given, it is read from the audio file where possible.
+type WWEpisode struct {
+	Season     int         `yaml:"season,omitempty"`
+	Number     int         `yaml:"episode,omitempty"`
+	Type       string      `yaml:"episode_type,omitempty"`
+	Explicit   bool        `yaml:"explicit,omitempty"`
+	Duration   string      `yaml:"duration,omitempty"`
+	Transcript string      `yaml:"transcript,omitempty"`
+	Chapters   []WWChapter `yaml:"chapters,omitempty"`
+}
+
+// An ingredient of a recipe. Quantity may be a whole number, a decimal, or a fraction such as "1 1/2". An ingredient
+// given as a plain string, such as "salt to taste", has only an item.
+type WWIngredient struct {
+	Quantity string `yaml:"quantity,omitempty"`
+	Unit     string `yaml:"unit,omitempty"`
+	Item     string `yaml:"item,omitempty"`
+	Note     string `yaml:"note,omitempty"`
+}
+
+func (i *WWIngredient) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		i.Item = node.Value
+		return nil
+	}
+	type plain WWIngredient
+	return node.Decode((*plain)(i))
+}
+
+// Nutrition facts per serving, each including its unit, e.g. "12 g".
+type WWNutrition struct {
+	Calories      string `yaml:"calories,omitempty"`
+	Fat           string `yaml:"fat,omitempty"`
+	SaturatedFat  string `yaml:"saturated_fat,omitempty"`
+	Cholesterol   string `yaml:"cholesterol,omitempty"`
+	Sodium        string `yaml:"sodium,omitempty"`
+	Carbohydrates string `yaml:"carbohydrates,omitempty"`
+	Fiber         string `yaml:"fiber,omitempty"`
+	Sugar         string `yaml:"sugar,omitempty"`
+	Protein       string `yaml:"protein,omitempty"`
+}
+
+// The structured part of a recipe. Times are given as durations such as 1h30m, or as a number of minutes. Yield
+// describes what the recipe makes, e.g. "24 cookies", and defaults to the number of servings.
+type WWRecipe struct {
+	Servings    int            `yaml:"servings,omitempty"`
+	Yield       string         `yaml:"yield,omitempty"`
+	PrepTime    string         `yaml:"prep_time,omitempty"`
+	CookTime    string         `yaml:"cook_time,omitempty"`
+	Category    string         `yaml:"category,omitempty"`
+	Cuisine     string         `yaml:"cuisine,omitempty"`
+	Ingredients []WWIngredient `yaml:"ingredients,omitempty"`
+	Steps       []string       `yaml:"steps,omitempty"`
+	Nutrition   WWNutrition    `yaml:"nutrition,omitempty"`
+}
+
+type WyWebRecipe struct {
+	HeadData `yaml:",inline"`
+	PageData `yaml:",inline"`
+	Index    string   `yaml:"index,omitempty"`
+	Preview  string   `yaml:"preview,omitempty"`
+	Tags     []string `yaml:"tags,omitempty"`
+	Series   WWSeries `yaml:"series,omitempty"`
+	WWRecipe `yaml:",inline"`
+}
+
+// The parts of a plain page that may be turned off. Everything is shown unless set to false.
+type WWPage struct {
+	Breadcrumbs *bool `yaml:"breadcrumbs,omitempty"`
+	Heading     *bool `yaml:"heading,omitempty"`
+	TOC         *bool `yaml:"toc,omitempty"`
+	Menu        *bool `yaml:"menu,omitempty"`
+}
+
+type WyWebPage struct {
+	HeadData `yaml:",inline"`
+	PageData `yaml:",inline"`
+	Index    string `yaml:"index,omitempty"`
+	WWPage   `yaml:",inline"`
+}
+
+type WyWebPodcast struct {
+	HeadData  `yaml:",inline"`
+	PageData  `yaml:",inline"`
+	WWPodcast `yaml:",inline"`
+}
+
+type WyWebEpisode struct {
+	HeadData  `yaml:",inline"`
+	PageData  `yaml:",inline"`
+	Tags      []string `yaml:"tags,omitempty"`
+	WWEpisode `yaml:",inline"`
+}
+
 // //////////////////////////////////////////////////////////////////////////////
 //
 //	WyWebRoot methods
@@ -342,16 +723,88 @@ func (m WyWebGallery) GetPageData() *PageData {
 
 // //////////////////////////////////////////////////////////////////////////////
 //
-//	WyWebPage methods
+//	WyWebPodcast methods
+//
+// //////////////////////////////////////////////////////////////////////////////
+func (m WyWebPodcast) GetPath() string {
+	return m.Path
+}
+
+func (m WyWebPodcast) GetType() WWNodeKind {
+	return WWPODCAST
+}
+
+func (m WyWebPodcast) GetHeadData() *HeadData {
+	return &m.HeadData
+}
+
+func (m WyWebPodcast) GetPageData() *PageData {
+	return &m.PageData
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+//
+//	WyWebEpisode methods
+//
+// //////////////////////////////////////////////////////////////////////////////
+func (m WyWebEpisode) GetPath() string {
+	return m.Path
+}
+
+// Episodes are posts in every respect but their audio.
+func (m WyWebEpisode) GetType() WWNodeKind {
+	return WWPOST
+}
+
+func (m WyWebEpisode) GetHeadData() *HeadData {
+	return &m.HeadData
+}
+
+func (m WyWebEpisode) GetPageData() *PageData {
+	return &m.PageData
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+//
+//	WyWebRecipe methods
 //
 // //////////////////////////////////////////////////////////////////////////////
-//func (m WyWebPage) GetPath() string {
-//	return m.Path
-//}
+func (m WyWebRecipe) GetPath() string {
+	return m.Path
+}
+
+func (m WyWebRecipe) GetType() WWNodeKind {
+	return WWRECIPE
+}
+
+func (m WyWebRecipe) GetHeadData() *HeadData {
+	return &m.HeadData
+}
+
+func (m WyWebRecipe) GetPageData() *PageData {
+	return &m.PageData
+}
+
+// //////////////////////////////////////////////////////////////////////////////
 //
-//func (m WyWebPage) GetType() string {
-//	return "page"
-//}
+//	WyWebPage methods
+//
+// //////////////////////////////////////////////////////////////////////////////
+func (m WyWebPage) GetPath() string {
+	return m.Path
+}
+
+func (m WyWebPage) GetType() WWNodeKind {
+	return WWPAGE
+}
+
+func (m WyWebPage) GetHeadData() *HeadData {
+	return &m.HeadData
+}
+
+func (m WyWebPage) GetPageData() *PageData {
+	return &m.PageData
+}
 
 type Document struct {
 	Data     WyWebMeta
@@ -391,12 +844,71 @@ func (d *Document) UnmarshalYAML(node *yaml.Node) error {
 			gallery.GalleryItems[idx].SetID()
 		}
 		d.Data = &gallery
+	case "!page":
+		var page WyWebPage
+		if err := node.Decode(&page); err != nil {
+			return err
+		}
+		d.Data = &page
+	case "!recipe":
+		var recipe WyWebRecipe
+		if err := node.Decode(&recipe); err != nil {
+			return err
+		}
+		d.Data = &recipe
+	case "!podcast":
+		var podcast WyWebPodcast
+		if err := node.Decode(&podcast); err != nil {
+			return err
+		}
+		d.Data = &podcast
+	case "!episode":
+		var episode WyWebEpisode
+		if err := node.Decode(&episode); err != nil {
+			return err
+		}
+		d.Data = &episode
 	default:
 		return fmt.Errorf("unknown tag: %s", node.Tag)
 	}
+	if data, ok := d.Data.(interface {
+		setTranslations(map[string]map[string]string)
+	}); ok {
+		data.setTranslations(collectTranslations(node))
+	}
 	return nil
 }
 
+func (p *PageData) setTranslations(translations map[string]map[string]string) {
+	p.Translations = translations
+}
+
+// The fields that may be given once per language, e.g. title.es
+var translatableFields = []string{"title", "description", "preview", "image", "index"}
+
+var translationKeyRegex = regexp.MustCompile(`^([a-z_]+)\.([A-Za-z]{2,3}(?:-[A-Za-z0-9]+)*)$`)
+
+// collectTranslations gathers the keys of a wyweb file of the form <field>.<lang>, which the typed structures ignore.
+func collectTranslations(node *yaml.Node) map[string]map[string]string {
+	out := make(map[string]map[string]string)
+	if node.Kind != yaml.MappingNode {
+		return out
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		match := translationKeyRegex.FindStringSubmatch(key.Value)
+		if match == nil || value.Kind != yaml.ScalarNode || !slices.Contains(translatableFields, match[1]) {
+			continue
+		}
+		lang := strings.ToLower(match[2])
+		if out[lang] == nil {
+			out[lang] = make(map[string]string)
+		}
+		out[lang][match[1]] = value.Value
+	}
+	return out
+}
+
 func ReadWyWeb(dir string, forceTag ...string) (WyWebMeta, error) {
 	stat, err := os.Stat(dir)
 	if err != nil {
@@ -41,6 +41,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -67,6 +68,16 @@ const fileNotFound = `
 
 var VERSION string
 
+// NotFound answers with the theme's error page if there is one.
+func NotFound(tree *ConfigTree, w http.ResponseWriter) {
+	w.WriteHeader(404)
+	if page, ok := tree.ErrorPage(404); ok {
+		w.Write(page)
+		return
+	}
+	w.Write([]byte(fileNotFound))
+}
+
 func GetRemoteAddr(req *http.Request) string {
 	forwarded := req.Header.Get("X-Forwarded-For")
 	if forwarded != "" {
@@ -84,12 +95,82 @@ func GetHost(req *http.Request) string {
 }
 
 func RouteTags(node *ConfigNode, taglist []string, w http.ResponseWriter, req *http.Request) {
-	crumbs, bcsd := Breadcrumbs(node, WWNavLink{Path: strings.TrimPrefix(req.URL.String(), "/"), Text: "Tags"})
+	crumbs, bcsd := Breadcrumbs(node, WWNavLink{Path: strings.TrimPrefix(req.URL.String(), "/"), Text: node.T("Tags")})
 	page := BuildTagListing(node, taglist, crumbs)
 	headData := node.Tree.GetDefaultHead()
-	headData.Title = "Tags"
+	headData.Title = node.T("Tags")
+	if headData.Lang != "" {
+		headData.Lang = node.Language()
+	}
+	page.Append(BuildFooter(node))
+	node.LocalizeLinks(page)
+	buf, _ := node.RenderDocument(page, *headData, bcsd)
+	w.Write(buf.Bytes())
+}
+
+// RouteTagFeed serves the feed of a single tag, requested as tags/<tag>/<feed file>.
+func RouteTagFeed(tree *ConfigTree, rest string, w http.ResponseWriter, req *http.Request) {
+	tag, filename := filepath.Split(rest)
+	tag = strings.TrimSuffix(tag, "/")
+	feed, contentType, err := tree.TagFeed(tag, filename)
+	if err != nil {
+		NotFound(tree, w)
+		return
+	}
+	w.Header().Add("content-type", contentType)
+	w.Write(feed)
+}
+
+func RouteSeries(tree *ConfigTree, name string, w http.ResponseWriter, req *http.Request) {
+	node := tree.Root.Variant(req.URL.Query().Get("lang"))
+	extraCrumbs := []WWNavLink{{Path: "/series", Text: node.T("Series")}}
+	title := node.T("Series")
+	if name != "" {
+		extraCrumbs = append(extraCrumbs, WWNavLink{Path: "/series/" + url.PathEscape(name), Text: name})
+		title = name
+	}
+	crumbs, bcsd := Breadcrumbs(node, extraCrumbs...)
+	page, err := BuildSeriesListing(node, name, crumbs)
+	if err != nil {
+		NotFound(tree, w)
+		return
+	}
+	headData := tree.GetDefaultHead()
+	headData.Title = title
+	if headData.Lang != "" {
+		headData.Lang = node.Language()
+	}
+	page.Append(BuildFooter(node))
+	node.LocalizeLinks(page)
+	buf, _ := node.RenderDocument(page, *headData, bcsd)
+	w.Write(buf.Bytes())
+}
+
+func RouteArchive(tree *ConfigTree, base string, year, month int, w http.ResponseWriter, req *http.Request) {
+	node := tree.Root
+	if base != "" {
+		var err error
+		node, err = tree.Search(base)
+		if err != nil {
+			NotFound(tree, w)
+			return
+		}
+	}
+	node = node.Variant(req.URL.Query().Get("lang"))
+	crumbs, bcsd := Breadcrumbs(node, ArchiveCrumbs(node, year, month)...)
+	page, err := BuildArchive(node, year, month, crumbs)
+	if err != nil {
+		NotFound(tree, w)
+		return
+	}
+	headData := tree.GetDefaultHead()
+	headData.Title = node.T("Archive")
+	if headData.Lang != "" {
+		headData.Lang = node.Language()
+	}
 	page.Append(BuildFooter(node))
-	buf, _ := BuildDocument(page, *headData, bcsd)
+	node.LocalizeLinks(page)
+	buf, _ := node.RenderDocument(page, *headData, bcsd)
 	w.Write(buf.Bytes())
 }
 
@@ -122,25 +203,66 @@ func RouteStatic(node *ConfigNode, w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
+	if _, ok := req.URL.Query()["chapters"]; ok {
+		chapters, err := node.ChaptersJSON()
+		if err != nil {
+			w.WriteHeader(404)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Header().Add("content-type", "application/json+chapters; charset=utf-8")
+		w.Write(chapters)
+		return
+	}
 	if node.HTML == nil {
 		switch node.NodeKind {
 		//case *WyWebRoot:
 		case WWLISTING:
 			err = BuildDirListing(node)
-		case WWPOST:
+		case WWPODCAST:
+			err = BuildPodcast(node)
+		case WWPOST, WWRECIPE:
 			err = BuildPost(node)
 		case WWGALLERY:
 			err = BuildGallery(node)
+		case WWPAGE:
+			err = BuildPage(node)
 		default:
 			w.WriteHeader(500)
 			return
 		}
-		node.HTML.Append(BuildFooter(node))
+		if !node.Footer.Hidden {
+			node.HTML.Append(BuildFooter(node))
+		}
+		node.LocalizeLinks(node.HTML)
 		node.LastRead = time.Now()
 	}
+	// The directives apply to every response for the page, so they are given before any is written.
+	for _, value := range node.Robots.HeaderValues() {
+		w.Header().Add("X-Robots-Tag", value)
+	}
 	if err != nil {
-		w.WriteHeader(404)
-		w.Write([]byte(fileNotFound))
+		NotFound(node.Tree, w)
+		return
+	}
+	if id := req.URL.Query().Get("image"); id != "" && node.NodeKind == WWGALLERY {
+		headData := node.GetHTMLHeadData()
+		for idx := range node.Images {
+			if node.Images[idx].GetIDb64() == id {
+				headData.Social = node.Images[idx].SocialMeta()
+				break
+			}
+		}
+		buf, _ := node.RenderDocument(node.HTML, *headData, node.DocumentStructuredData()...)
+		w.Write(buf.Bytes())
+		return
+	}
+	if servings, units, ok := RecipeOptions(req.URL.Query()); ok && node.Recipe != nil {
+		// A rescaled recipe is built for this response alone, leaving the cached page as written.
+		page := RescaledRecipe(node, servings, units)
+		buf, _ := node.RenderDocument(page, *node.GetHTMLHeadData(), node.DocumentStructuredData()...)
+		w.Write(buf.Bytes())
+		return
 	}
 	buf, _ := node.BuildDocument()
 	w.Write(buf.Bytes())
@@ -193,25 +315,54 @@ func (r WyWebHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 	raw := strings.TrimPrefix(req.URL.Path, "/")
 	path, _ := filepath.Rel(".", raw)
+	lang := req.URL.Query().Get("lang")
 	if raw == "tags" {
 		taglist := req.URL.Query()["tags"]
-		RouteTags(realm.Root, taglist, w, req)
+		RouteTags(realm.Root.Variant(lang), taglist, w, req)
+		return
+	}
+	if raw == "robots.txt" {
+		w.Header().Add("content-type", "text/plain; charset=utf-8")
+		w.Write(realm.RobotsTxt())
+		return
+	}
+	if sitemap, ok := realm.GetSitemap(raw); ok {
+		w.Header().Add("content-type", "application/xml; charset=utf-8")
+		w.Write(sitemap)
+		return
+	}
+	if content, contentType, ok := realm.GetAsset(raw); ok {
+		w.Header().Add("content-type", contentType)
+		w.Header().Add("cache-control", ImmutableCacheControl)
+		w.Write(content)
+		return
+	}
+	if strings.HasPrefix(raw, "tags/") {
+		RouteTagFeed(realm, strings.TrimPrefix(raw, "tags/"), w, req)
+		return
+	}
+	if raw == "series" || strings.HasPrefix(raw, "series/") {
+		name := strings.Trim(strings.TrimPrefix(raw, "series"), "/")
+		RouteSeries(realm, name, w, req)
 		return
 	}
 	node, err := realm.Search(path)
 	if err != nil {
+		if base, year, month, ok := ParseArchivePath(path); ok {
+			RouteArchive(realm, base, year, month, w, req)
+			return
+		}
 		_, ok := os.Stat(filepath.Join(path, "wyweb"))
 		if ok != nil {
-			w.WriteHeader(404)
-			w.Write([]byte(fileNotFound))
+			NotFound(realm, w)
 			return
 		}
-		w.WriteHeader(404)
-		w.Write([]byte(fileNotFound))
+		NotFound(realm, w)
 		log.Printf(err.Error())
 		return
 	}
 
+	node = node.Variant(lang)
 	if taglist, ok := req.URL.Query()["tags"]; ok {
 		RouteTags(node, taglist, w, req)
 		return
@@ -94,6 +94,7 @@ func ConcatUnique[T comparable](sliceA []T, sliceB []T) []T {
 func TrimMagicSuffix(str string) string {
 	suffixes := []string{
 		".post.md",
+		".page.md",
 		".listing",
 	}
 	out := strings.Clone(str)